@@ -0,0 +1,147 @@
+package paypal
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type CircuitState int
+
+const (
+	CIRCUIT_CLOSED    CircuitState = iota // requests are sent
+	CIRCUIT_OPEN                          // requests fail with a *CircuitOpenError
+	CIRCUIT_HALF_OPEN                     // a few probe requests are sent to see if PayPal is back
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CIRCUIT_OPEN:
+		return "open"
+	case CIRCUIT_HALF_OPEN:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// CircuitOpenError is returned without sending the request while the circuit
+// breaker is open.
+type CircuitOpenError struct {
+	RetryAt time.Time // when probe requests will be let through again
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "paypal: circuit breaker open until " + e.RetryAt.Format(time.RFC3339)
+}
+
+// CircuitBreaker stops a client from sending requests while PayPal is down,
+// so callers fail fast instead of waiting for timeouts. It opens after
+// FailureThreshold transport errors, timeouts, HTTP 5xx or maintenance
+// responses in a row. After OpenTimeout it lets up to HalfOpenProbes requests
+// through: the first one to succeed closes the circuit, a failure opens it
+// again.
+//
+// Any answer from PayPal other than an internal error, including errors
+// about the request itself, counts as success.
+type CircuitBreaker struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenProbes   int // defaults to 1
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probes   int // in flight while half-open
+}
+
+// NewCircuitBreaker creates a breaker opening after failureThreshold
+// consecutive failures and probing again after openTimeout.
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{FailureThreshold: failureThreshold, OpenTimeout: openTimeout, HalfOpenProbes: 1}
+}
+
+// WithCircuitBreaker guards the client's requests with breaker. A breaker
+// may be shared between clients that talk to the same endpoint.
+func WithCircuitBreaker(breaker *CircuitBreaker) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.breaker = breaker
+	}
+}
+
+// CircuitState reports the state of the client's circuit breaker, e.g. for a
+// health check. Clients without a breaker are always CIRCUIT_CLOSED.
+func (pClient *PayPalClient) CircuitState() CircuitState {
+	if pClient.breaker == nil {
+		return CIRCUIT_CLOSED
+	}
+	return pClient.breaker.State()
+}
+
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CIRCUIT_OPEN && time.Since(b.openedAt) >= b.OpenTimeout {
+		return CIRCUIT_HALF_OPEN
+	}
+	return b.state
+}
+
+// allow reports whether a request may be sent. Every allowed request must be
+// followed by a call to record, or to release if it wasn't sent after all.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CIRCUIT_OPEN {
+		if retryAt := b.openedAt.Add(b.OpenTimeout); time.Now().Before(retryAt) {
+			return &CircuitOpenError{RetryAt: retryAt}
+		}
+		b.state = CIRCUIT_HALF_OPEN
+		b.probes = 0
+	}
+
+	if b.state == CIRCUIT_HALF_OPEN {
+		maxProbes := b.HalfOpenProbes
+		if maxProbes < 1 {
+			maxProbes = 1
+		}
+		if b.probes >= maxProbes {
+			return &CircuitOpenError{RetryAt: time.Now().Add(b.OpenTimeout)}
+		}
+		b.probes++
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.probes > 0 {
+		b.probes--
+	}
+}
+
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.probes > 0 {
+		b.probes--
+	}
+
+	switch {
+	case errors.Is(err, ErrCanceled):
+		// says nothing about PayPal
+	case IsRetryable(err) || errors.Is(err, ErrDeadlineExceeded):
+		b.failures++
+		if b.state == CIRCUIT_HALF_OPEN || b.failures >= b.FailureThreshold {
+			b.state = CIRCUIT_OPEN
+			b.openedAt = time.Now()
+			b.probes = 0
+		}
+	default:
+		b.state = CIRCUIT_CLOSED
+		b.failures = 0
+	}
+}
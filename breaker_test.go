@@ -0,0 +1,58 @@
+package paypal_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls int
+	down := true
+	breaker := paypal.NewCircuitBreaker(2, 20*time.Millisecond)
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if down {
+			w.Write([]byte("<html>PayPal is down for maintenance</html>"))
+			return
+		}
+		w.Write([]byte("ACK=Failure&L_ERRORCODE0=10410&L_SHORTMESSAGE0=Invalid+token"))
+	}, paypal.WithCircuitBreaker(breaker))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); !errors.Is(err, paypal.ErrInternal) {
+			t.Fatalf("Expected a maintenance error, got %v", err)
+		}
+	}
+	if client.CircuitState() != paypal.CIRCUIT_OPEN {
+		t.Fatalf("Expected the circuit to open, got %v", client.CircuitState())
+	}
+
+	var openError *paypal.CircuitOpenError
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); !errors.As(err, &openError) || calls != 2 {
+		t.Fatalf("Expected to fail fast without calling PayPal, got %v after %d calls", err, calls)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if breaker.State() != paypal.CIRCUIT_HALF_OPEN {
+		t.Fatalf("Expected the circuit to half-open, got %v", breaker.State())
+	}
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); !errors.Is(err, paypal.ErrInternal) {
+		t.Fatalf("Expected the probe to fail, got %v", err)
+	}
+	if breaker.State() != paypal.CIRCUIT_OPEN {
+		t.Fatalf("Expected a failed probe to open the circuit again, got %v", breaker.State())
+	}
+
+	down = false
+	time.Sleep(30 * time.Millisecond)
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); !errors.Is(err, paypal.ErrInvalidToken) {
+		t.Fatalf("Expected the probe to reach PayPal, got %v", err)
+	}
+	if breaker.State() != paypal.CIRCUIT_CLOSED {
+		t.Errorf("Expected a request error from PayPal to close the circuit, got %v", breaker.State())
+	}
+}
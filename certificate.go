@@ -0,0 +1,93 @@
+package paypal
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+)
+
+// NVP endpoints for API certificate credentials
+const (
+	NVP_CERTIFICATE_SANDBOX_URL    = "https://api.sandbox.paypal.com/nvp"
+	NVP_CERTIFICATE_PRODUCTION_URL = "https://api.paypal.com/nvp"
+)
+
+// ErrCertificateTransport is returned by NewCertificateClient when the
+// http.Client's Transport isn't an *http.Transport, so the certificate can't
+// be added to it.
+var ErrCertificateTransport = errors.New("paypal: can't add the API certificate to the http.Client's Transport")
+
+// CertificateCredentials authenticate with an API certificate instead of an
+// API signature: the certificate is presented as a TLS client certificate
+// and no SIGNATURE is sent.
+type CertificateCredentials struct {
+	Username    string
+	Password    string
+	Certificate tls.Certificate
+}
+
+// NewCertificateCredentials parses a PEM encoded certificate and private key,
+// as downloaded from PayPal's API access page.
+func NewCertificateCredentials(username, password string, certPEM, keyPEM []byte) (*CertificateCredentials, error) {
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &CertificateCredentials{Username: username, Password: password, Certificate: certificate}, nil
+}
+
+// LoadCertificateCredentials reads the PEM encoded certificate and private key
+// from files. Both may be the same file.
+func LoadCertificateCredentials(username, password, certFile, keyFile string) (*CertificateCredentials, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &CertificateCredentials{Username: username, Password: password, Certificate: certificate}, nil
+}
+
+// NewCertificateClient creates a client using API certificate credentials,
+// talking to the api.paypal.com endpoints rather than the api-3t ones used
+// with signatures.
+//
+// The certificate is added to a copy of the http.Client's transport. If the
+// client set with WithHTTPClient has a Transport that isn't an
+// *http.Transport (such as App Engine's urlfetch), ErrCertificateTransport
+// is returned rather than a client that would send its requests without
+// the certificate.
+func NewCertificateClient(credentials *CertificateCredentials, options ...ClientOption) (*PayPalClient, error) {
+	certificate := credentials.Certificate
+	options = append([]ClientOption{func(pClient *PayPalClient) {
+		pClient.certificate = &certificate
+	}}, options...)
+
+	pClient := NewClientWithOptions(credentials.Username, credentials.Password, "", options...)
+	client, err := withClientCertificate(pClient.client, pClient.certificate)
+	if err != nil {
+		return nil, err
+	}
+	pClient.client = client
+	return pClient, nil
+}
+
+// withClientCertificate returns a copy of client presenting certificate.
+func withClientCertificate(client *http.Client, certificate *tls.Certificate) (*http.Client, error) {
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, ErrCertificateTransport
+	}
+
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.Certificates = append([]tls.Certificate{*certificate}, transport.TLSClientConfig.Certificates...)
+
+	clientCopy := *client
+	clientCopy.Transport = transport
+	return &clientCopy, nil
+}
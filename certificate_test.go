@@ -0,0 +1,86 @@
+package paypal_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal"
+)
+
+func testCertificatePEM(t *testing.T) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "api-user"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func TestCertificateClient(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if len(r.TLS.PeerCertificates) != 1 || r.TLS.PeerCertificates[0].Subject.CommonName != "api-user" {
+			t.Errorf("Expected the API certificate to be presented")
+		}
+		if _, ok := r.PostForm["SIGNATURE"]; ok || r.PostForm.Get("USER") != "user" {
+			t.Errorf("Expected USER and PWD without SIGNATURE, got %v", r.PostForm)
+		}
+		w.Write([]byte("ACK=Success&TOKEN=EC-123"))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	certPEM, keyPEM := testCertificatePEM(t)
+	credentials, err := paypal.NewCertificateCredentials("user", "pass", certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("NewCertificateCredentials returned error: %v", err)
+	}
+
+	client, err := paypal.NewCertificateClient(credentials, paypal.WithEndpoint(server.URL), paypal.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("NewCertificateClient returned error: %v", err)
+	}
+	if _, err := client.CreateBillingAgreementContext(t.Context(), "EC-123"); err != nil {
+		t.Fatalf("CreateBillingAgreementContext returned error: %v", err)
+	}
+
+	// a transport the certificate can't be added to would send unauthenticated requests
+	custom := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+	if _, err := paypal.NewCertificateClient(credentials, paypal.WithHTTPClient(custom)); err != paypal.ErrCertificateTransport {
+		t.Errorf("Expected ErrCertificateTransport for a custom transport, got %v", err)
+	}
+
+	if _, err := paypal.NewCertificateCredentials("user", "pass", []byte("not a certificate"), keyPEM); err == nil {
+		t.Errorf("Expected an error for an invalid certificate")
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,546 @@
+package paypal_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal"
+	"github.com/crowdmob/paypal/nvp"
+)
+
+// fakeNVP answers every NVP call with response, after handing the decoded
+// request values to inspect.
+func fakeNVP(t *testing.T, inspect func(url.Values), response url.Values, options ...fakeNVPOption) *paypal.PayPalClient {
+	var config fakeNVPConfig
+	for _, option := range options {
+		option(&config)
+	}
+
+	calls := 0
+	return fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Couldn't parse NVP request: %v", err)
+		}
+		if inspect != nil {
+			inspect(r.PostForm)
+		}
+		if calls++; calls <= config.failures {
+			w.WriteHeader(config.status)
+			return
+		}
+		w.Write([]byte(response.Encode()))
+	}, config.clientOptions...)
+}
+
+type fakeNVPConfig struct {
+	failures      int
+	status        int
+	clientOptions []paypal.ClientOption
+}
+
+type fakeNVPOption func(*fakeNVPConfig)
+
+// failFirst makes fakeNVP answer the first n calls with the HTTP status.
+func failFirst(n, status int) fakeNVPOption {
+	return func(config *fakeNVPConfig) {
+		config.failures, config.status = n, status
+	}
+}
+
+// withClientOptions creates the fakeNVP client with options.
+func withClientOptions(options ...paypal.ClientOption) fakeNVPOption {
+	return func(config *fakeNVPConfig) {
+		config.clientOptions = append(config.clientOptions, options...)
+	}
+}
+
+// fakeNVPServer returns a client whose requests are all served by handler.
+func fakeNVPServer(t *testing.T, handler http.HandlerFunc, options ...paypal.ClientOption) *paypal.PayPalClient {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	options = append([]paypal.ClientOption{paypal.WithEndpoint(server.URL)}, options...)
+	return paypal.NewClientWithOptions("user", "pass", "sig", options...)
+}
+
+func TestCancelInFlight(t *testing.T) {
+	received := make(chan struct{})
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// the server only notices the client going away once the body is read
+		r.ParseForm()
+		close(received)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	go func() {
+		<-received
+		cancel()
+	}()
+
+	_, err := client.GetExpressCheckoutDetailsContext(ctx, "EC-123")
+	if !errors.Is(err, paypal.ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected ErrCanceled wrapping context.Canceled, got %v", err)
+	}
+	if errors.Is(err, paypal.ErrDeadlineExceeded) || paypal.IsRetryable(err) {
+		t.Errorf("Expected a canceled call to be neither a timeout nor retryable, got %v", err)
+	}
+
+	if _, err := client.GetExpressCheckoutDetailsContext(ctx, "EC-123"); !errors.Is(err, paypal.ErrCanceled) {
+		t.Errorf("Expected ErrCanceled for an already canceled context, got %v", err)
+	}
+}
+
+func TestDoExpressCheckoutPayment(t *testing.T) {
+	client := fakeNVP(t, func(v url.Values) {
+		if v.Get("METHOD") != "DoExpressCheckoutPayment" || v.Get("PAYMENTREQUEST_0_AMT") != "12.50" || v.Get("PAYMENTREQUEST_0_PAYMENTACTION") != "Sale" {
+			t.Errorf("Unexpected request: %v", v)
+		}
+	}, url.Values{
+		"ACK":                                 {"Success"},
+		"TOKEN":                               {"EC-123"},
+		"REDIRECTREQUIRED":                    {"false"},
+		"SUCCESSPAGEREDIRECTREQUESTED":        {"true"},
+		"PAYMENTINFO_0_TRANSACTIONID":         {"TX0"},
+		"PAYMENTINFO_0_AMT":                   {"12.50"},
+		"PAYMENTINFO_0_CURRENCYCODE":          {"USD"},
+		"PAYMENTINFO_0_SELLERPAYPALACCOUNTID": {"seller@example.com"},
+		"PAYMENTINFO_0_ERRORCODE":             {"0"},
+		"PAYMENTINFO_1_TRANSACTIONID":         {"TX1"},
+		"PAYMENTINFO_1_AMT":                   {"1000"},
+		"PAYMENTINFO_1_CURRENCYCODE":          {"JPY"},
+		"PAYMENTINFO_1_ERRORCODE":             {"10417"},
+		"PAYMENTINFO_1_SHORTMESSAGE":          {"Transaction cannot complete."},
+		"L_PAYMENTINFO_1_FMFPENDINGID0":       {"1"},
+		"L_PAYMENTINFO_1_FMFPENDINGNAME0":     {"AVS No Match"},
+	})
+
+	response, err := client.DoExpressCheckoutSaleContext(t.Context(), "EC-123", "PAYER", paypal.NewMoney(1250, "USD"))
+	if err != nil {
+		t.Fatalf("DoExpressCheckoutSaleContext returned error: %v", err)
+	}
+
+	if response.Token != "EC-123" || response.RedirectRequired || !response.SuccessPageRedirectRequested {
+		t.Errorf("Unexpected response header fields: %#v", response)
+	}
+	if len(response.PaymentsInfo) != 2 || len(response.Sellers) != 2 {
+		t.Fatalf("Expected 2 payments, got %d", len(response.PaymentsInfo))
+	}
+	if response.PaymentsInfo[1].TransactionId != "TX1" || !response.PaymentsInfo[1].Amount.Equal(paypal.NewMoney(1000, "JPY")) {
+		t.Errorf("Unexpected second payment: %#v", response.PaymentsInfo[1])
+	}
+	if response.Sellers[0].SellerPayPalAccountId != "seller@example.com" {
+		t.Errorf("Unexpected seller info: %#v", response.Sellers[0])
+	}
+	if len(response.PaymentErrors) != 1 || response.PaymentErrors[0].PaymentRequest != 1 || response.PaymentErrors[0].ErrorCode != "10417" {
+		t.Errorf("Unexpected payment errors: %#v", response.PaymentErrors)
+	}
+	if len(response.FraudFilters) != 1 || response.FraudFilters[0].Action != "Pending" || response.FraudFilters[0].Name != "AVS No Match" {
+		t.Errorf("Unexpected fraud filters: %#v", response.FraudFilters)
+	}
+}
+
+func TestPartialSuccess(t *testing.T) {
+	calls := 0
+	client := fakeNVP(t, func(url.Values) { calls++ }, url.Values{
+		"ACK":                            {"PartialSuccess"},
+		"TOKEN":                          {"EC-123"},
+		"PAYMENTINFO_0_TRANSACTIONID":    {"TX0"},
+		"PAYMENTINFO_0_PAYMENTREQUESTID": {"order-1-seller-a"},
+		"PAYMENTINFO_0_ERRORCODE":        {"0"},
+		"PAYMENTINFO_1_PAYMENTREQUESTID": {"order-1-seller-b"},
+		"PAYMENTINFO_1_ERRORCODE":        {"10001"},
+		"PAYMENTINFO_1_SHORTMESSAGE":     {"Internal Error"},
+	})
+
+	response, err := client.DoExpressCheckoutPaymentsContext(t.Context(), "EC-123", "PAYER", []paypal.PaymentRequest{
+		{Amount: paypal.NewMoney(1500, "USD"), PaymentRequestId: "order-1-seller-a", SellerPayPalAccountId: "a@example.com"},
+		{Amount: paypal.NewMoney(800, "USD"), PaymentRequestId: "order-1-seller-b", SellerPayPalAccountId: "b@example.com"},
+	})
+	var pError *paypal.PayPalError
+	if !errors.As(err, &pError) || pError.ErrorCode != "10001" || pError.Errors[0].PaymentRequest != 1 {
+		t.Fatalf("Expected a PayPalError for the failed payment, got %v", err)
+	}
+	if response == nil || response.IsSuccess() || response.IsWarning() || !response.IsPartialSuccess() {
+		t.Fatalf("Expected the partial success to be returned as neither success nor warning, got %+v", response)
+	}
+	if payment, ok := response.Payment("order-1-seller-a"); !ok || payment.TransactionId != "TX0" {
+		t.Errorf("Expected the payment that went through, got %+v", response.PaymentsInfo)
+	}
+	if calls != 1 || paypal.IsRetryable(err) {
+		t.Errorf("Expected a partial success not to be retried, got %d calls", calls)
+	}
+}
+
+func TestParallelPayments(t *testing.T) {
+	requests := []paypal.PaymentRequest{
+		{
+			Amount:                 paypal.NewMoney(1500, "USD"),
+			ItemAmount:             paypal.NewMoney(1200, "USD"),
+			ShippingAmount:         paypal.NewMoney(500, "USD"),
+			ShippingDiscount:       paypal.NewMoney(200, "USD"),
+			InsuranceOptionOffered: true,
+			PaymentAction:          "Sale",
+			PaymentRequestId:       "order-1-seller-a",
+			SellerPayPalAccountId:  "a@example.com",
+			Items: []paypal.PaymentItem{
+				{Name: "Mug", Amount: paypal.NewMoney(600, "USD"), Quantity: 2},
+			},
+		},
+		{
+			Amount:                paypal.NewMoney(800, "USD"),
+			PaymentAction:         "Sale",
+			PaymentRequestId:      "order-1-seller-b",
+			SellerPayPalAccountId: "b@example.com",
+		},
+	}
+
+	client := fakeNVP(t, func(v url.Values) {
+		expected := map[string]string{
+			"PAYMENTREQUEST_0_AMT":                    "15.00",
+			"PAYMENTREQUEST_0_CURRENCYCODE":           "USD",
+			"PAYMENTREQUEST_0_ITEMAMT":                "12.00",
+			"PAYMENTREQUEST_0_SHIPDISCAMT":            "-2.00",
+			"PAYMENTREQUEST_0_INSURANCEOPTIONOFFERED": "true",
+			"PAYMENTREQUEST_0_PAYMENTREQUESTID":       "order-1-seller-a",
+			"PAYMENTREQUEST_0_SELLERPAYPALACCOUNTID":  "a@example.com",
+			"L_PAYMENTREQUEST_0_NAME0":                "Mug",
+			"L_PAYMENTREQUEST_0_QTY0":                 "2",
+			"PAYMENTREQUEST_1_AMT":                    "8.00",
+			"PAYMENTREQUEST_1_PAYMENTREQUESTID":       "order-1-seller-b",
+			"PAYMENTREQUEST_1_SELLERPAYPALACCOUNTID":  "b@example.com",
+		}
+		for key, value := range expected {
+			if v.Get(key) != value {
+				t.Errorf("Expected %s=%s in %s request, got %q", key, value, v.Get("METHOD"), v.Get(key))
+			}
+		}
+	}, url.Values{
+		"ACK":                                 {"Success"},
+		"TOKEN":                               {"EC-123"},
+		"PAYMENTINFO_0_TRANSACTIONID":         {"TX0"},
+		"PAYMENTINFO_0_PAYMENTREQUESTID":      {"order-1-seller-a"},
+		"PAYMENTINFO_1_TRANSACTIONID":         {"TX1"},
+		"PAYMENTINFO_1_PAYMENTREQUESTID":      {"order-1-seller-b"},
+		"PAYMENTINFO_1_SELLERPAYPALACCOUNTID": {"b@example.com"},
+	})
+
+	checkout, err := client.SetExpressCheckoutContext(t.Context(), &paypal.ExpressCheckout{
+		ReturnUrl:       "https://example.com/ok",
+		CancelUrl:       "https://example.com/cancel",
+		PaymentRequests: requests,
+	})
+	if err != nil || checkout.Token != "EC-123" {
+		t.Fatalf("SetExpressCheckoutContext returned %v, %v", checkout, err)
+	}
+
+	response, err := client.DoExpressCheckoutPaymentsContext(t.Context(), "EC-123", "PAYER", requests)
+	if err != nil {
+		t.Fatalf("DoExpressCheckoutPaymentsContext returned error: %v", err)
+	}
+	if payment, ok := response.Payment("order-1-seller-b"); !ok || payment.TransactionId != "TX1" || response.Sellers[1].SellerPayPalAccountId != "b@example.com" {
+		t.Errorf("Unexpected payments %+v", response.PaymentsInfo)
+	}
+
+	requests[1].PaymentRequestId = requests[0].PaymentRequestId
+	var validationError *paypal.ValidationError
+	if _, err := client.DoExpressCheckoutPaymentsContext(t.Context(), "EC-123", "PAYER", requests); !errors.As(err, &validationError) || validationError.Field != "PAYMENTREQUEST_1_PAYMENTREQUESTID" {
+		t.Errorf("Expected a ValidationError for the duplicate id, got %v", err)
+	}
+	requests[1].PaymentRequestId = "order-1-seller-b"
+	requests[1].TaxAmount = paypal.NewMoney(-100, "USD")
+	if _, err := client.DoExpressCheckoutPaymentsContext(t.Context(), "EC-123", "PAYER", requests); !errors.As(err, &validationError) || validationError.Field != "PAYMENTREQUEST_1_TAXAMT" {
+		t.Errorf("Expected a ValidationError for the negative tax, got %v", err)
+	}
+	if _, err := client.DoExpressCheckoutPaymentsContext(t.Context(), "EC-123", "PAYER", make([]paypal.PaymentRequest, paypal.MAX_PAYMENT_REQUESTS+1)); !errors.As(err, &validationError) {
+		t.Errorf("Expected a ValidationError for too many payment requests, got %v", err)
+	}
+}
+
+func TestPaymentRequestCurrency(t *testing.T) {
+	client := fakeNVP(t, func(v url.Values) {
+		for key, value := range map[string]string{
+			"PAYMENTREQUEST_0_AMT":          "1000",
+			"PAYMENTREQUEST_0_CURRENCYCODE": "JPY",
+			"L_PAYMENTREQUEST_0_AMT0":       "500",
+		} {
+			if v.Get(key) != value {
+				t.Errorf("Expected %s=%s, got %q", key, value, v.Get(key))
+			}
+		}
+		if v.Has("PAYMENTREQUEST_0_TAXAMT") {
+			t.Errorf("Expected no TAXAMT for a zero tax, got %q", v.Get("PAYMENTREQUEST_0_TAXAMT"))
+		}
+	}, url.Values{"ACK": {"Success"}, "TOKEN": {"EC-123"}})
+
+	items := []paypal.PaymentItem{{Name: "Mug", Amount: paypal.NewMoney(500, ""), Quantity: 2}}
+	requests := []paypal.PaymentRequest{{Amount: paypal.NewMoney(1000, ""), CurrencyCode: "JPY", Items: items}}
+	if _, err := client.DoExpressCheckoutPaymentsContext(t.Context(), "EC-123", "PAYER", requests); err != nil {
+		t.Fatalf("DoExpressCheckoutPaymentsContext returned error: %v", err)
+	}
+	if items[0].Amount.Currency != "" {
+		t.Errorf("Expected the caller's items to be left alone, got %+v", items[0].Amount)
+	}
+}
+
+func TestFloatAmounts(t *testing.T) {
+	var sent url.Values
+	client := fakeNVP(t, func(v url.Values) { sent = v }, url.Values{"ACK": {"Success"}})
+
+	if _, err := client.RefundTransaction(100, 0, 0, "TX", "", "", "JPY", true); err != nil || sent.Get("AMT") != "100" {
+		t.Errorf("Expected AMT=100, got %q, %v", sent.Get("AMT"), err)
+	}
+	if _, err := client.RefundTransaction(19.99, 0, 0.1+0.2, "TX", "", "", "USD", true); err != nil || sent.Get("AMT") != "19.99" || sent.Get("TAXAMT") != "0.30" {
+		t.Errorf("Expected AMT=19.99 and TAXAMT=0.30, got %q and %q, %v", sent.Get("AMT"), sent.Get("TAXAMT"), err)
+	}
+
+	sent = nil
+	var validationError *paypal.ValidationError
+	if _, err := client.RefundTransaction(100.5, 0, 0, "TX", "", "", "JPY", true); !errors.As(err, &validationError) || validationError.Field != "AMT" {
+		t.Errorf("Expected a ValidationError for 100.5 JPY, got %v", err)
+	}
+	if _, err := client.DoReferenceTransaction("B-123", "Sale", 12.345); !errors.As(err, &validationError) || validationError.Field != "AMT" {
+		t.Errorf("Expected a ValidationError for 12.345 USD, got %v", err)
+	}
+	if sent != nil {
+		t.Errorf("Expected nothing to be sent for inexact amounts, got %v", sent)
+	}
+}
+
+func TestGetExpressCheckoutDetails(t *testing.T) {
+	client := fakeNVP(t, nil, url.Values{
+		"ACK":                                {"Success"},
+		"TOKEN":                              {"EC-123"},
+		"CHECKOUTSTATUS":                     {"PaymentActionNotInitiated"},
+		"PAYERSTATUS":                        {"verified"},
+		"BUYERMARKETINGEMAIL":                {"buyer@example.com"},
+		"GIFTRECEIPTENABLE":                  {"true"},
+		"GIFTWRAPAMOUNT":                     {"2.00"},
+		"PAYMENTREQUEST_0_AMT":               {"15.50"},
+		"PAYMENTREQUEST_0_CURRENCYCODE":      {"EUR"},
+		"PAYMENTREQUEST_0_ITEMAMT":           {"12.00"},
+		"PAYMENTREQUEST_0_SHIPPINGAMT":       {"3.50"},
+		"PAYMENTREQUEST_0_INVNUM":            {"INV-1"},
+		"PAYMENTREQUEST_0_NOTETEXT":          {"Leave at the door"},
+		"PAYMENTREQUEST_0_SHIPTONAME":        {"Jane Doe"},
+		"PAYMENTREQUEST_0_SHIPTOSTREET":      {"1 Main St"},
+		"PAYMENTREQUEST_0_ADDRESSSTATUS":     {"Confirmed"},
+		"L_PAYMENTREQUEST_0_NAME0":           {"Book"},
+		"L_PAYMENTREQUEST_0_AMT0":            {"6.00"},
+		"L_PAYMENTREQUEST_0_QTY0":            {"2"},
+		"PAYMENTREQUEST_1_AMT":               {"4.00"},
+		"PAYMENTREQUEST_1_CURRENCYCODE":      {"USD"},
+		"PAYMENTREQUEST_1_TRANSACTIONID":     {"8KB86281CJ123456A"},
+		"PAYMENTREQUEST_1_PAYMENTREQUESTID":  {"seller-2"},
+		"PAYMENTREQUEST_1_SHIPTOCOUNTRYCODE": {"US"},
+		"L_PAYMENTREQUEST_1_ITEMCATEGORY0":   {"Digital"},
+		"PAYMENTREQUEST_3_AMT":               {"1.00"}, // after a gap, so not a payment request
+	})
+
+	details, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123")
+	if err != nil {
+		t.Fatalf("GetExpressCheckoutDetailsContext returned error: %v", err)
+	}
+	if details.CheckoutStatus != paypal.CHECKOUT_STATUS_NOT_INITIATED || details.CheckoutStatus.IsCompleted() {
+		t.Errorf("Unexpected checkout status %q", details.CheckoutStatus)
+	}
+	if !details.PayerStatusVerified || details.BuyerMarketingEmail != "buyer@example.com" || !details.GiftReceiptEnabled || details.GiftWrapAmount.String() != "2.00 EUR" {
+		t.Errorf("Unexpected buyer details %+v", details)
+	}
+	if len(details.PaymentRequests) != 2 || len(details.ShippingAddresses) != 2 || len(details.PaymentsInfo) != 2 {
+		t.Fatalf("Expected 2 payment requests, got %d, %d addresses and %d payments", len(details.PaymentRequests), len(details.ShippingAddresses), len(details.PaymentsInfo))
+	}
+
+	request := details.PaymentRequests[0]
+	if request.Amount.String() != "15.50 EUR" || request.ItemAmount.String() != "12.00 EUR" || request.ShippingAmount.String() != "3.50 EUR" {
+		t.Errorf("Unexpected amounts %+v", request)
+	}
+	if request.InvoiceId != "INV-1" || request.NoteText != "Leave at the door" || request.ShippingAddress.Name != "Jane Doe" {
+		t.Errorf("Unexpected payment request %+v", request)
+	}
+	if len(request.Items) != 1 || request.Items[0].Name != "Book" || request.Items[0].Quantity != 2 || request.Items[0].Amount.String() != "6.00 EUR" {
+		t.Errorf("Unexpected items %+v", request.Items)
+	}
+	if details.ShippingAddresses[0] != request.ShippingAddress || details.ShippingAddresses[1].CountryCode != "US" {
+		t.Errorf("Unexpected shipping addresses %+v", details.ShippingAddresses)
+	}
+	if details.PaymentRequests[1].PaymentRequestId != "seller-2" || details.PaymentRequests[1].Items[0].ItemCategory != "Digital" {
+		t.Errorf("Unexpected second payment request %+v", details.PaymentRequests[1])
+	}
+	if details.PaymentsInfo[1].TransactionId != "8KB86281CJ123456A" || details.PaymentsInfo[1].Amount.String() != "4.00 USD" {
+		t.Errorf("Unexpected payments %+v", details.PaymentsInfo)
+	}
+}
+
+func TestMalformedResponseValues(t *testing.T) {
+	client := fakeNVP(t, nil, url.Values{
+		"ACK":                 {"Success"},
+		"REFUNDTRANSACTIONID": {"RTX0"},
+		"GROSSREFUNDAMT":      {"abc"},
+		"NETREFUNDAMT":        {"9.50"},
+		"CURRENCYCODE":        {"USD"},
+	})
+	response, err := client.RefundTransactionContext(t.Context(), paypal.Money{}, paypal.Money{}, paypal.Money{}, "TX0", "", "", false)
+	if err != nil {
+		t.Fatalf("Expected an acknowledged call to succeed despite a malformed value, got %v", err)
+	}
+
+	var decodeError *nvp.DecodeError
+	if !errors.As(response.DecodeErr, &decodeError) || len(decodeError.Errors) != 1 || decodeError.Errors[0].Key != "GROSSREFUNDAMT" {
+		t.Fatalf("Expected a DecodeError for GROSSREFUNDAMT, got %v", response.DecodeErr)
+	}
+	if response.RefundTransactionId != "RTX0" || response.NetRefundAmount.String() != "9.50 USD" {
+		t.Errorf("Expected the rest of the response, got %+v", response)
+	}
+}
+
+func TestPayPalErrorCollectsAllErrors(t *testing.T) {
+	client := fakeNVP(t, nil, url.Values{
+		"ACK":                           {"Failure"},
+		"L_ERRORCODE0":                  {"10004"},
+		"L_SHORTMESSAGE0":               {"Transaction refused because of an invalid argument."},
+		"L_SEVERITYCODE0":               {"Error"},
+		"L_ERRORCODE1":                  {"10413"},
+		"L_SHORTMESSAGE1":               {"Transaction refused because of an invalid argument."},
+		"L_SEVERITYCODE1":               {"Error"},
+		"PAYMENTREQUEST_1_ERRORCODE":    {"10412"},
+		"PAYMENTREQUEST_1_SHORTMESSAGE": {"Duplicate invoice"},
+	})
+
+	_, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123")
+	pError, ok := err.(*paypal.PayPalError)
+	if !ok {
+		t.Fatalf("Expected a *PayPalError, got %#v", err)
+	}
+
+	if len(pError.Errors) != 3 {
+		t.Fatalf("Expected 3 errors, got %#v", pError.Errors)
+	}
+	if pError.ErrorCode != "10004" || pError.Errors[1].Index != 1 || pError.Errors[2].PaymentRequest != 1 {
+		t.Errorf("Unexpected errors: %#v", pError.Errors)
+	}
+	for _, code := range []string{"10004", "10413", "10412 (payment request 1)"} {
+		if !strings.Contains(pError.Error(), code) {
+			t.Errorf("Error() = %q, should mention %s", pError.Error(), code)
+		}
+	}
+}
+
+func TestPayPalErrorCategories(t *testing.T) {
+	client := fakeNVP(t, nil, url.Values{
+		"ACK":             {"Failure"},
+		"L_ERRORCODE0":    {"10004"},
+		"L_SHORTMESSAGE0": {"Transaction refused because of an invalid argument."},
+		"L_ERRORCODE1":    {paypal.ERROR_CODE_FUNDING_FAILURE},
+		"L_SHORTMESSAGE1": {"This transaction couldn't be completed."},
+	})
+
+	_, err := client.DoExpressCheckoutSaleContext(t.Context(), "EC-123", "PAYER", paypal.NewMoney(100, "USD"))
+	if !errors.Is(err, paypal.ErrFundingFailure) {
+		t.Errorf("Expected errors.Is(err, ErrFundingFailure) for %v", err)
+	}
+	if errors.Is(err, paypal.ErrDuplicateRequest) {
+		t.Errorf("Didn't expect errors.Is(err, ErrDuplicateRequest) for %v", err)
+	}
+
+	var pError *paypal.PayPalError
+	if !errors.As(err, &pError) || pError.Errors[1].Category() != paypal.ErrFundingFailure {
+		t.Errorf("Expected errors.As to find the *PayPalError in %v", err)
+	}
+
+	if !errors.Is(&paypal.PayPalError{}, paypal.ErrInternal) {
+		t.Errorf("An empty response should be reported as ErrInternal")
+	}
+}
+
+func TestSuccessWithWarning(t *testing.T) {
+	client := fakeNVP(t, nil, url.Values{
+		"ACK":                 {"SuccessWithWarning"},
+		"REFUNDTRANSACTIONID": {"RTX"},
+		"L_ERRORCODE0":        {paypal.ERROR_CODE_DUPLICATE_REQUEST},
+		"L_SHORTMESSAGE0":     {"Duplicate Request"},
+		"L_SEVERITYCODE0":     {"Warning"},
+	})
+
+	response, err := client.RefundTransactionContext(t.Context(), paypal.Money{}, paypal.Money{}, paypal.Money{}, "TX", "", "key", false)
+	if err != nil {
+		t.Fatalf("SuccessWithWarning should not be reported as an error, got %v", err)
+	}
+	if !response.IsSuccess() || !response.IsWarning() {
+		t.Errorf("Expected IsSuccess and IsWarning for ACK %s", response.Ack)
+	}
+	if response.RefundTransactionId != "RTX" || len(response.Warnings) != 1 || response.Warnings[0].Category() != paypal.ErrDuplicateRequest {
+		t.Errorf("Unexpected response: %#v", response)
+	}
+
+	client = fakeNVP(t, nil, url.Values{
+		"ACK":             {"FailureWithWarning"},
+		"L_ERRORCODE0":    {"10009"},
+		"L_SEVERITYCODE0": {"Warning"},
+	})
+	if _, err := client.RefundTransactionContext(t.Context(), paypal.Money{}, paypal.Money{}, paypal.Money{}, "TX", "", "key", false); err == nil {
+		t.Errorf("FailureWithWarning should be reported as an error")
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	client := fakeNVP(t, nil, url.Values{
+		"ACK":                   {"Success"},
+		"TIMESTAMP":             {"2014-01-28T22:41:39Z"},
+		"TRANSACTIONID":         {"TX0"},
+		"ORDERTIME":             {"2014-01-28T22:41:38Z"},
+		"BILLINGAGREEMENTID":    {"B-123"},
+		"AMT":                   {"5.00"},
+		"CURRENCYCODE":          {"USD"},
+		"PAYMENTSTATUS":         {"Completed"},
+		"PROTECTIONELIGIBILITY": {"Eligible"},
+	})
+	response, err := client.DoReferenceTransactionContext(t.Context(), "B-123", "Sale", paypal.NewMoney(500, "USD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Timestamp.Equal(time.Date(2014, 1, 28, 22, 41, 39, 0, time.UTC)) || response.RawTimestamp != "2014-01-28T22:41:39Z" {
+		t.Errorf("Unexpected timestamp %v (%q)", response.Timestamp, response.RawTimestamp)
+	}
+	if !response.OrderTime.Equal(time.Date(2014, 1, 28, 22, 41, 38, 0, time.UTC)) {
+		t.Errorf("Unexpected order time %v", response.OrderTime)
+	}
+
+	client = fakeNVP(t, nil, url.Values{
+		"ACK":           {"Success"},
+		"TIMESTAMP":     {"28/01/2014 22:41:39"},
+		"TRANSACTIONID": {"TX0"},
+		"ORDERTIME":     {"2014-01-28 22:41:38"},
+	})
+	response, err = client.DoReferenceTransactionContext(t.Context(), "B-123", "Sale", paypal.NewMoney(500, "USD"))
+	if err != nil {
+		t.Fatalf("Expected an acknowledged call to succeed despite malformed times, got %v", err)
+	}
+	var timestampError *paypal.TimestampError
+	if !errors.As(response.TimestampErr, &timestampError) || timestampError.Field != "TIMESTAMP" || !response.Timestamp.IsZero() {
+		t.Errorf("Expected a TimestampError for TIMESTAMP, got %v", response.TimestampErr)
+	}
+	if !errors.As(response.OrderTimeErr, &timestampError) || timestampError.Field != "ORDERTIME" || response.RawOrderTime != "2014-01-28 22:41:38" {
+		t.Errorf("Expected a TimestampError for ORDERTIME, got %v", response.OrderTimeErr)
+	}
+	if timestamp, err := paypal.ParseTimestamp("TIMESTAMP", "2014-01-28T23:41:39.5+01:00"); err != nil || !timestamp.Equal(time.Date(2014, 1, 28, 22, 41, 39, 5e8, time.UTC)) {
+		t.Errorf("Expected any RFC 3339 time to be accepted, got %v, %v", timestamp, err)
+	}
+	if response.TransactionId != "TX0" {
+		t.Errorf("Expected the rest of the response, got %+v", response)
+	}
+
+	massPay, err := client.MassPayContext(t.Context(), paypal.NewMoney(500, "USD"), "Payout", "T-1", "", "EmailAddress", "payee@example.com")
+	if err != nil || massPay.TimestampErr == nil {
+		t.Errorf("Expected MassPay to succeed with TimestampErr set, got %v, %v", massPay, err)
+	}
+}
@@ -0,0 +1,129 @@
+package paypal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Credentials are the API username, password and signature sent as USER, PWD
+// and SIGNATURE. Signature is empty for API certificate credentials.
+type Credentials struct {
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	Signature string `json:"signature"`
+}
+
+// CredentialProvider is asked for credentials on every request, so they can
+// be rotated without rebuilding clients.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// StaticCredentials always returns the same credentials.
+type StaticCredentials Credentials
+
+func (c StaticCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// EnvCredentials reads the credentials from the environment variables
+// Prefix+"USERNAME", Prefix+"PASSWORD" and Prefix+"SIGNATURE" on every
+// request, e.g. PAYPAL_TEST_USERNAME for the prefix "PAYPAL_TEST_".
+type EnvCredentials struct {
+	Prefix string
+}
+
+func (c EnvCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	credentials := Credentials{
+		Username:  os.Getenv(c.Prefix + "USERNAME"),
+		Password:  os.Getenv(c.Prefix + "PASSWORD"),
+		Signature: os.Getenv(c.Prefix + "SIGNATURE"),
+	}
+	if len(credentials.Username) == 0 || len(credentials.Password) == 0 {
+		return Credentials{}, fmt.Errorf("paypal: environment variables %sUSERNAME and %sPASSWORD must be set", c.Prefix, c.Prefix)
+	}
+	return credentials, nil
+}
+
+// FileCredentials reads the credentials from a JSON file
+//
+//	{"username": "...", "password": "...", "signature": "..."}
+//
+// and reloads it whenever its modification time or size changes. If a
+// reload fails, e.g. because the file is being rewritten, the previous
+// credentials are kept and LastError reports why.
+type FileCredentials struct {
+	path string
+
+	mu          sync.Mutex
+	credentials Credentials
+	modTime     time.Time
+	size        int64
+	lastErr     error
+}
+
+// NewFileCredentials loads the credentials file at path, failing if it can't
+// be read.
+func NewFileCredentials(path string) (*FileCredentials, error) {
+	c := &FileCredentials{path: path}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *FileCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(c.path)
+	switch {
+	case err != nil:
+		// reload the file once it's back, whatever its modification time
+		c.modTime, c.size = time.Time{}, -1
+		c.lastErr = err
+	case !info.ModTime().Equal(c.modTime) || info.Size() != c.size:
+		// a failed reload is recorded too, so a broken file is only read
+		// again once it changes
+		c.modTime, c.size = info.ModTime(), info.Size()
+		c.lastErr = c.reload()
+	}
+	return c.credentials, nil
+}
+
+// LastError returns the error of the last attempt to reload the file, or nil
+// if it succeeded.
+func (c *FileCredentials) LastError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastErr
+}
+
+// reload must be called with c.mu held, or before c is shared.
+func (c *FileCredentials) reload() error {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+
+	var credentials Credentials
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return fmt.Errorf("paypal: invalid credentials file %s: %w", c.path, err)
+	}
+	if len(credentials.Username) == 0 || len(credentials.Password) == 0 {
+		return fmt.Errorf("paypal: credentials file %s has no username or password", c.path)
+	}
+
+	c.credentials = credentials
+	c.modTime = info.ModTime()
+	c.size = info.Size()
+	return nil
+}
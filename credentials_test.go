@@ -0,0 +1,100 @@
+package paypal_test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal"
+)
+
+// recordSignature appends the USER and SIGNATURE of every call to signatures.
+func recordSignature(signatures *[]string) func(url.Values) {
+	return func(v url.Values) { *signatures = append(*signatures, v.Get("USER")+":"+v.Get("SIGNATURE")) }
+}
+
+func TestFileCredentialsReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "paypal.json")
+	if err := os.WriteFile(path, []byte(`{"username": "user", "password": "pass", "signature": "old"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := paypal.NewFileCredentials(path)
+	if err != nil {
+		t.Fatalf("NewFileCredentials returned error: %v", err)
+	}
+
+	var signatures []string
+	client := fakeNVP(t, recordSignature(&signatures), url.Values{"ACK": {"Success"}}, withClientOptions(paypal.WithCredentialProvider(provider)))
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"username": "user", "password": "pass", "signature": "rotated"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.LastError(); err != nil {
+		t.Errorf("Expected no error after a good reload, got %v", err)
+	}
+
+	// a broken rewrite keeps the last good credentials
+	os.WriteFile(path, []byte(`{"username": `), 0600)
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.LastError(); err == nil {
+		t.Errorf("Expected LastError to report the broken file")
+	}
+
+	os.Remove(path)
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.LastError(); !os.IsNotExist(err) {
+		t.Errorf("Expected LastError to report the missing file, got %v", err)
+	}
+
+	// the file is read again once it's back
+	os.WriteFile(path, []byte(`{"username": "user", "password": "pass", "signature": "restored"}`), 0600)
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := provider.LastError(); err != nil {
+		t.Errorf("Expected no error once the file is restored, got %v", err)
+	}
+
+	want := []string{"user:old", "user:rotated", "user:rotated", "user:rotated", "user:restored"}
+	for i := range want {
+		if i >= len(signatures) || signatures[i] != want[i] {
+			t.Fatalf("Expected credentials %q, got %q", want, signatures)
+		}
+	}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	t.Setenv("PAYPAL_UNIT_USERNAME", "env-user")
+	t.Setenv("PAYPAL_UNIT_PASSWORD", "env-pass")
+	t.Setenv("PAYPAL_UNIT_SIGNATURE", "env-sig")
+
+	var signatures []string
+	client := fakeNVP(t, recordSignature(&signatures), url.Values{"ACK": {"Success"}}, withClientOptions(paypal.WithCredentialProvider(paypal.EnvCredentials{Prefix: "PAYPAL_UNIT_"})))
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+		t.Fatal(err)
+	}
+	if len(signatures) != 1 || signatures[0] != "env-user:env-sig" {
+		t.Errorf("Expected the credentials from the environment, got %q", signatures)
+	}
+
+	client = fakeNVP(t, recordSignature(&signatures), url.Values{"ACK": {"Success"}}, withClientOptions(paypal.WithCredentialProvider(paypal.EnvCredentials{Prefix: "PAYPAL_MISSING_"})))
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err == nil {
+		t.Errorf("Expected an error when the environment variables are missing")
+	}
+}
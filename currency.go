@@ -0,0 +1,102 @@
+package paypal
+
+import (
+	"net/url"
+	"strings"
+)
+
+type CurrencyInfo struct {
+	Code      string
+	Digits    int   // minor-unit digits, 0 for JPY, HUF and TWD
+	MaxAmount int64 // maximum amount per transaction, in minor units
+}
+
+// PayPal's supported currencies and their per-transaction limits
+var currencies = map[string]CurrencyInfo{
+	"AUD": {"AUD", 2, 12500 * 100},
+	"BRL": {"BRL", 2, 20000 * 100}, // Brazilian accounts only
+	"CAD": {"CAD", 2, 12500 * 100},
+	"CHF": {"CHF", 2, 13000 * 100},
+	"CZK": {"CZK", 2, 240000 * 100},
+	"DKK": {"DKK", 2, 60000 * 100},
+	"EUR": {"EUR", 2, 8000 * 100},
+	"GBP": {"GBP", 2, 5500 * 100},
+	"HKD": {"HKD", 2, 60000 * 100},
+	"HUF": {"HUF", 0, 2000000},
+	"ILS": {"ILS", 2, 40000 * 100},
+	"JPY": {"JPY", 0, 1000000},
+	"MXN": {"MXN", 2, 110000 * 100},
+	"MYR": {"MYR", 2, 40000 * 100}, // Malaysian accounts only
+	"NOK": {"NOK", 2, 70000 * 100},
+	"NZD": {"NZD", 2, 14000 * 100},
+	"PHP": {"PHP", 2, 500000 * 100},
+	"PLN": {"PLN", 2, 32000 * 100},
+	"SEK": {"SEK", 2, 80000 * 100},
+	"SGD": {"SGD", 2, 16000 * 100},
+	"THB": {"THB", 2, 360000 * 100},
+	"TRY": {"TRY", 2, 25000 * 100}, // Turkish accounts only
+	"TWD": {"TWD", 0, 330000},
+	"USD": {"USD", 2, 10000 * 100},
+}
+
+// LookupCurrency returns the PayPal currency table entry for code.
+func LookupCurrency(code string) (CurrencyInfo, bool) {
+	info, ok := currencies[strings.ToUpper(code)]
+	return info, ok
+}
+
+// ValidationError is returned before a request is sent when one of its
+// fields would be rejected by PayPal.
+type ValidationError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return "paypal: invalid " + e.Field + " " + e.Value + ": " + e.Reason
+}
+
+// ValidateAmount checks m against the currency table. Amounts without a
+// currency are only checked for sign, since PayPal applies the account's
+// default currency to them.
+func ValidateAmount(field string, m Money) error {
+	if m.IsNegative() {
+		return &ValidationError{Field: field, Value: m.String(), Reason: "amount must not be negative"}
+	}
+	return validateCurrency(field, m)
+}
+
+// validateCurrency checks m against the currency table like ValidateAmount,
+// but not its sign, which depends on the field: discounts are negative.
+func validateCurrency(field string, m Money) error {
+	if len(m.Currency) == 0 {
+		return nil
+	}
+
+	info, ok := LookupCurrency(m.Currency)
+	if !ok {
+		return &ValidationError{Field: field, Value: m.String(), Reason: "currency not supported by PayPal"}
+	}
+	if m.Minor > info.MaxAmount || -m.Minor > info.MaxAmount {
+		max := Money{Minor: info.MaxAmount, Currency: info.Code}
+		return &ValidationError{Field: field, Value: m.String(), Reason: "amount exceeds the " + max.String() + " transaction limit"}
+	}
+	return nil
+}
+
+// addAmount validates m and adds it to values, formatted for its currency.
+func addAmount(values url.Values, field string, m Money) error {
+	if err := ValidateAmount(field, m); err != nil {
+		return err
+	}
+	values.Add(field, m.Decimal())
+	return nil
+}
+
+func currencyDigits(currency string) int {
+	if info, ok := LookupCurrency(currency); ok {
+		return info.Digits
+	}
+	return 2
+}
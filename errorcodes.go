@@ -0,0 +1,121 @@
+package paypal
+
+// ErrorCategory groups NVP error codes by what the caller should do about
+// them. The categories are errors themselves, so they can be matched with
+// errors.Is:
+//
+//	if errors.Is(err, paypal.ErrFundingFailure) {
+//		// send the buyer back to PayPal to pick another funding source
+//	}
+type ErrorCategory int
+
+const (
+	ErrUnknown            ErrorCategory = iota
+	ErrAuthentication                   // bad API credentials or missing permissions
+	ErrInvalidToken                     // missing, expired or foreign Express Checkout token
+	ErrFundingFailure                   // buyer has to choose another funding source
+	ErrDuplicateRequest                 // request was already processed, e.g. same MSGSUBID or invoice
+	ErrTransactionRefused               // PayPal refused the transaction itself
+	ErrInternal                         // PayPal internal error or maintenance; safe to try again later
+)
+
+// Well-known NVP error codes
+const (
+	ERROR_CODE_INTERNAL_ERROR          = "10001"
+	ERROR_CODE_AUTHENTICATION_FAILED   = "10002"
+	ERROR_CODE_PERMISSION_DENIED       = "10007"
+	ERROR_CODE_TOKEN_MISSING           = "10408"
+	ERROR_CODE_TOKEN_NOT_AUTHORIZED    = "10409"
+	ERROR_CODE_INVALID_TOKEN           = "10410"
+	ERROR_CODE_SESSION_EXPIRED         = "10411"
+	ERROR_CODE_DUPLICATE_INVOICE       = "10412"
+	ERROR_CODE_TOKEN_ALREADY_USED      = "10415"
+	ERROR_CODE_FUNDING_UNAVAILABLE     = "10417"
+	ERROR_CODE_NEW_FUNDING_REQUIRED    = "10422"
+	ERROR_CODE_FUNDING_FAILURE         = "10486"
+	ERROR_CODE_BILLING_AGREEMENT_ENDED = "10201"
+	ERROR_CODE_ACCOUNT_RESTRICTED      = "10204"
+	ERROR_CODE_TRANSACTION_REFUSED     = "10009"
+	ERROR_CODE_TRANSACTION_REJECTED    = "10606"
+	ERROR_CODE_RISK_REFUSED            = "10626"
+	ERROR_CODE_DUPLICATE_REQUEST       = "11607"
+)
+
+var errorCategories = map[string]ErrorCategory{
+	ERROR_CODE_INTERNAL_ERROR:          ErrInternal,
+	ERROR_CODE_AUTHENTICATION_FAILED:   ErrAuthentication,
+	ERROR_CODE_PERMISSION_DENIED:       ErrAuthentication,
+	ERROR_CODE_TOKEN_MISSING:           ErrInvalidToken,
+	ERROR_CODE_TOKEN_NOT_AUTHORIZED:    ErrInvalidToken,
+	ERROR_CODE_INVALID_TOKEN:           ErrInvalidToken,
+	ERROR_CODE_SESSION_EXPIRED:         ErrInvalidToken,
+	ERROR_CODE_DUPLICATE_INVOICE:       ErrDuplicateRequest,
+	ERROR_CODE_TOKEN_ALREADY_USED:      ErrDuplicateRequest,
+	ERROR_CODE_FUNDING_UNAVAILABLE:     ErrFundingFailure,
+	ERROR_CODE_NEW_FUNDING_REQUIRED:    ErrFundingFailure,
+	ERROR_CODE_FUNDING_FAILURE:         ErrFundingFailure,
+	ERROR_CODE_BILLING_AGREEMENT_ENDED: ErrFundingFailure,
+	ERROR_CODE_ACCOUNT_RESTRICTED:      ErrFundingFailure,
+	ERROR_CODE_TRANSACTION_REFUSED:     ErrTransactionRefused,
+	ERROR_CODE_TRANSACTION_REJECTED:    ErrTransactionRefused,
+	ERROR_CODE_RISK_REFUSED:            ErrTransactionRefused,
+	ERROR_CODE_DUPLICATE_REQUEST:       ErrDuplicateRequest,
+}
+
+// CategoryOf returns the category of an NVP error code, or ErrUnknown.
+func CategoryOf(errorCode string) ErrorCategory {
+	return errorCategories[errorCode]
+}
+
+func (c ErrorCategory) Error() string {
+	switch c {
+	case ErrAuthentication:
+		return "paypal: authentication failed"
+	case ErrInvalidToken:
+		return "paypal: invalid token"
+	case ErrFundingFailure:
+		return "paypal: funding failure"
+	case ErrDuplicateRequest:
+		return "paypal: duplicate request"
+	case ErrTransactionRefused:
+		return "paypal: transaction refused"
+	case ErrInternal:
+		return "paypal: internal error"
+	}
+	return "paypal: unknown error"
+}
+
+func (e ErrorEntry) Category() ErrorCategory {
+	return CategoryOf(e.ErrorCode)
+}
+
+// Category returns the category of the first error PayPal reported. A
+// response without any error code or ACK means PayPal is in maintenance,
+// which is reported as ErrInternal.
+func (e *PayPalError) Category() ErrorCategory {
+	if len(e.ErrorCode) != 0 {
+		return CategoryOf(e.ErrorCode)
+	}
+	if len(e.Ack) == 0 && len(e.ShortMessage) == 0 {
+		return ErrInternal
+	}
+	return ErrUnknown
+}
+
+// Is reports whether any of the errors in e belongs to the ErrorCategory
+// target.
+func (e *PayPalError) Is(target error) bool {
+	category, ok := target.(ErrorCategory)
+	if !ok {
+		return false
+	}
+	if e.Category() == category {
+		return true
+	}
+	for _, entry := range e.Errors {
+		if entry.Category() == category {
+			return true
+		}
+	}
+	return false
+}
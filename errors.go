@@ -0,0 +1,137 @@
+package paypal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrCanceled and ErrDeadlineExceeded wrap the context error when a request's
+// context is canceled or times out before PayPal responds.
+var (
+	ErrCanceled         = errors.New("paypal: request canceled")
+	ErrDeadlineExceeded = errors.New("paypal: request deadline exceeded")
+)
+
+// PayPalError is returned when PayPal does not acknowledge a request. The
+// ErrorCode, ShortMessage, LongMessage and SeverityCode fields repeat the
+// first entry of Errors.
+type PayPalError struct {
+	Ack          string
+	ErrorCode    string
+	ShortMessage string
+	LongMessage  string
+	SeverityCode string
+	Errors       []ErrorEntry
+}
+
+// ErrorEntry is one of the errors in an NVP response, either a top-level
+// L_ERRORCODEn or one scoped to a payment request.
+type ErrorEntry struct {
+	Index          int    // n of L_ERRORCODEn, 0 for payment request errors
+	PaymentRequest int    // n of PAYMENTREQUEST_n or PAYMENTINFO_n, -1 if the error applies to the whole call
+	Ack            string // only set for payment request errors
+	ErrorCode      string
+	ShortMessage   string
+	LongMessage    string
+	SeverityCode   string // "Error" or "Warning"
+}
+
+func (e ErrorEntry) String() string {
+	message := e.ErrorCode
+	if e.PaymentRequest >= 0 {
+		message += " (payment request " + strconv.Itoa(e.PaymentRequest) + ")"
+	}
+	return message + ": " + e.ShortMessage
+}
+
+func (e *PayPalError) Error() string {
+	var message string
+	if len(e.Errors) > 1 {
+		entries := make([]string, len(e.Errors))
+		for i, entry := range e.Errors {
+			entries[i] = entry.String()
+		}
+		message = "PayPal Errors " + strings.Join(entries, "; ")
+	} else if len(e.ErrorCode) != 0 && len(e.ShortMessage) != 0 {
+		message = "PayPal Error " + e.ErrorCode + ": " + e.ShortMessage
+	} else if len(e.ShortMessage) != 0 {
+		message = "PayPal Error: " + e.ShortMessage
+	} else if len(e.Ack) != 0 {
+		message = e.Ack
+	} else {
+		message = "PayPal is undergoing maintenance.\nPlease try again later."
+	}
+
+	return message
+}
+
+// parseErrors collects every error in an NVP response: the indexed
+// L_ERRORCODEn list followed by PAYMENTREQUEST_n_ERRORCODE and
+// PAYMENTINFO_n_ERRORCODE for each payment request.
+func parseErrors(values url.Values) []ErrorEntry {
+	var entries []ErrorEntry
+	for i := 0; ; i++ {
+		idx := strconv.Itoa(i)
+		errorCode := values.Get("L_ERRORCODE" + idx)
+		if len(errorCode) == 0 {
+			break
+		}
+		entries = append(entries, ErrorEntry{
+			Index:          i,
+			PaymentRequest: -1,
+			ErrorCode:      errorCode,
+			ShortMessage:   values.Get("L_SHORTMESSAGE" + idx),
+			LongMessage:    values.Get("L_LONGMESSAGE" + idx),
+			SeverityCode:   values.Get("L_SEVERITYCODE" + idx),
+		})
+	}
+
+	for _, section := range []string{"PAYMENTREQUEST", "PAYMENTINFO"} {
+		for n := 0; n < MAX_PAYMENT_REQUESTS; n++ {
+			prefix := fmt.Sprintf("%s_%d_", section, n)
+			errorCode := values.Get(prefix + "ERRORCODE")
+			// PAYMENTINFO_n_ERRORCODE is 0 for payments that went through
+			if len(errorCode) == 0 || errorCode == "0" {
+				continue
+			}
+			entries = append(entries, ErrorEntry{
+				PaymentRequest: n,
+				Ack:            values.Get(prefix + "ACK"),
+				ErrorCode:      errorCode,
+				ShortMessage:   values.Get(prefix + "SHORTMESSAGE"),
+				LongMessage:    values.Get(prefix + "LONGMESSAGE"),
+				SeverityCode:   values.Get(prefix + "SEVERITYCODE"),
+			})
+		}
+	}
+
+	return entries
+}
+
+func newPayPalError(ack string, entries []ErrorEntry) *PayPalError {
+	pError := &PayPalError{Ack: ack, Errors: entries}
+	if len(entries) != 0 {
+		pError.ErrorCode = entries[0].ErrorCode
+		pError.ShortMessage = entries[0].ShortMessage
+		pError.LongMessage = entries[0].LongMessage
+		pError.SeverityCode = entries[0].SeverityCode
+	}
+	return pError
+}
+
+// contextError reports err as ErrCanceled or ErrDeadlineExceeded when it was
+// caused by ctx, and returns it unchanged otherwise.
+func contextError(ctx context.Context, err error) error {
+	switch ctxErr := ctx.Err(); {
+	case ctxErr == nil:
+		return err
+	case errors.Is(ctxErr, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrDeadlineExceeded, ctxErr)
+	default:
+		return fmt.Errorf("%w: %w", ErrCanceled, ctxErr)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/crowdmob/paypal
+
+go 1.24
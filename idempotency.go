@@ -0,0 +1,122 @@
+package paypal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"sync"
+)
+
+// NVP methods that move money, which always get a MSGSUBID so that a retried
+// or repeated request is never processed twice
+var moneyMovingMethods = map[string]bool{
+	"DoExpressCheckoutPayment": true,
+	"DoReferenceTransaction":   true,
+	"RefundTransaction":        true,
+	"MassPay":                  true,
+}
+
+// IdempotencyStore remembers the MSGSUBID sent for an operation, so that
+// calling the same operation again (after a timeout, a crash, or from another
+// process) reuses the key and PayPal answers with the original result
+// instead of charging twice.
+type IdempotencyStore interface {
+	Load(ctx context.Context, operation string) (key string, ok bool, err error)
+	Store(ctx context.Context, operation, key string) error
+}
+
+type idempotencyContextKey int
+
+const (
+	idempotencyKeyContextKey idempotencyContextKey = iota
+	idempotencyOperationContextKey
+)
+
+// WithIdempotencyKey makes the request sent with ctx use key as its MSGSUBID.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey, key)
+}
+
+// WithIdempotentOperation names the operation a request performs, e.g.
+// "refund:order-42". The client's IdempotencyStore is consulted for a key
+// already used for that operation, and a new one is stored otherwise.
+func WithIdempotentOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, idempotencyOperationContextKey, operation)
+}
+
+func (pClient *PayPalClient) SetIdempotencyStore(store IdempotencyStore) {
+	pClient.idempotencyStore = store
+}
+
+// applyIdempotencyKey sets MSGSUBID on money-moving requests that don't have
+// one yet, from ctx, the idempotency store, or a freshly generated key.
+func (pClient *PayPalClient) applyIdempotencyKey(ctx context.Context, values url.Values) error {
+	if !moneyMovingMethods[values.Get("METHOD")] || len(values.Get("MSGSUBID")) != 0 {
+		return nil
+	}
+
+	if key, ok := ctx.Value(idempotencyKeyContextKey).(string); ok && len(key) != 0 {
+		values.Set("MSGSUBID", key)
+		return nil
+	}
+
+	operation, _ := ctx.Value(idempotencyOperationContextKey).(string)
+	if len(operation) != 0 && pClient.idempotencyStore != nil {
+		key, ok, err := pClient.idempotencyStore.Load(ctx, operation)
+		if err != nil {
+			return err
+		}
+		if ok {
+			values.Set("MSGSUBID", key)
+			return nil
+		}
+	}
+
+	key, err := NewIdempotencyKey()
+	if err != nil {
+		return err
+	}
+	if len(operation) != 0 && pClient.idempotencyStore != nil {
+		if err := pClient.idempotencyStore.Store(ctx, operation, key); err != nil {
+			return err
+		}
+	}
+
+	values.Set("MSGSUBID", key)
+	return nil
+}
+
+// NewIdempotencyKey returns a random key suitable for MSGSUBID, which
+// PayPal limits to 38 characters.
+func NewIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore for a single process.
+type MemoryIdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{keys: make(map[string]string)}
+}
+
+func (s *MemoryIdempotencyStore) Load(ctx context.Context, operation string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[operation]
+	return key, ok, nil
+}
+
+func (s *MemoryIdempotencyStore) Store(ctx context.Context, operation, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[operation] = key
+	return nil
+}
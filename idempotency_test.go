@@ -0,0 +1,60 @@
+package paypal_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal"
+)
+
+// recordKey appends the MSGSUBID of every call to keys.
+func recordKey(keys *[]string) func(url.Values) {
+	return func(v url.Values) { *keys = append(*keys, v.Get("MSGSUBID")) }
+}
+
+func TestIdempotencyKeyReusedOnRetry(t *testing.T) {
+	var keys []string
+	client := fakeNVP(t, recordKey(&keys), url.Values{"ACK": {"Success"}, "TRANSACTIONID": {"TX"}}, failFirst(1, http.StatusServiceUnavailable))
+	client.SetRetryPolicy(&paypal.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+	if _, err := client.DoReferenceTransactionContext(t.Context(), "B-123", "Sale", paypal.NewMoney(100, "USD")); err != nil {
+		t.Fatalf("DoReferenceTransactionContext returned error: %v", err)
+	}
+	if len(keys) != 2 || len(keys[0]) == 0 || keys[0] != keys[1] {
+		t.Errorf("Expected the generated MSGSUBID to be reused on retry, got %q", keys)
+	}
+}
+
+func TestIdempotencyKeyFromContextAndStore(t *testing.T) {
+	var keys []string
+	client := fakeNVP(t, recordKey(&keys), url.Values{"ACK": {"Success"}, "TRANSACTIONID": {"TX"}})
+	client.SetIdempotencyStore(paypal.NewMemoryIdempotencyStore())
+
+	ctx := paypal.WithIdempotencyKey(context.Background(), "my-key")
+	if _, err := client.DoExpressCheckoutSaleContext(ctx, "EC-123", "PAYER", paypal.NewMoney(100, "USD")); err != nil {
+		t.Fatalf("DoExpressCheckoutSaleContext returned error: %v", err)
+	}
+
+	ctx = paypal.WithIdempotentOperation(context.Background(), "charge:order-42")
+	for i := 0; i < 2; i++ {
+		if _, err := client.DoReferenceTransactionContext(ctx, "B-123", "Sale", paypal.NewMoney(100, "USD")); err != nil {
+			t.Fatalf("DoReferenceTransactionContext returned error: %v", err)
+		}
+	}
+	if _, err := client.MassPayContext(context.Background(), paypal.NewMoney(100, "USD"), "", "", "", "EmailAddress", "a@example.com"); err != nil {
+		t.Fatalf("MassPayContext returned error: %v", err)
+	}
+
+	if keys[0] != "my-key" {
+		t.Errorf("Expected the key from the context to be used, got %q", keys[0])
+	}
+	if len(keys[1]) == 0 || keys[1] != keys[2] {
+		t.Errorf("Expected both calls for the same operation to share a key, got %q and %q", keys[1], keys[2])
+	}
+	if len(keys[3]) == 0 || keys[3] == keys[1] {
+		t.Errorf("Expected a fresh key for an unrelated call, got %q", keys[3])
+	}
+}
@@ -0,0 +1,79 @@
+package paypal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WithLogger makes the client log one record per NVP call: at Info when
+// PayPal acknowledges it, Warn when it succeeds with warnings, and Error when
+// it fails. Only the amounts of the request are logged, never its other
+// values.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.logger = logger
+	}
+}
+
+func (pClient *PayPalClient) logRequest(ctx context.Context, values url.Values, response *PayPalResponse, err error, duration time.Duration) {
+	if pClient.logger == nil {
+		return
+	}
+
+	level := slog.LevelInfo
+	attrs := []slog.Attr{
+		slog.String("method", values.Get("METHOD")),
+		slog.Duration("duration", duration),
+	}
+	if response != nil {
+		attrs = append(attrs, slog.String("ack", response.Ack), slog.String("correlation_id", response.CorrelationId))
+		if response.IsWarning() {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.Any("error_codes", errorCodes(response.Warnings)))
+		}
+	}
+	if err != nil {
+		level = slog.LevelError
+		var pError *PayPalError
+		if errors.As(err, &pError) {
+			attrs = append(attrs, slog.Any("error_codes", errorCodes(pError.Errors)))
+		}
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if amounts := requestAmounts(values); len(amounts) != 0 {
+		attrs = append(attrs, slog.Attr{Key: "amounts", Value: slog.GroupValue(amounts...)})
+	}
+
+	pClient.logger.LogAttrs(ctx, level, "paypal: NVP call", attrs...)
+}
+
+func errorCodes(entries []ErrorEntry) []string {
+	codes := make([]string, len(entries))
+	for i, entry := range entries {
+		codes[i] = entry.ErrorCode
+	}
+	return codes
+}
+
+// requestAmounts returns the amount and currency fields of an NVP request,
+// e.g. PAYMENTREQUEST_0_AMT and PAYMENTREQUEST_0_CURRENCYCODE.
+func requestAmounts(values url.Values) []slog.Attr {
+	var keys []string
+	for key := range values {
+		if base := baseKey(key); strings.HasSuffix(base, "AMT") || base == "CURRENCYCODE" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, len(keys))
+	for i, key := range keys {
+		attrs[i] = slog.String(key, values.Get(key))
+	}
+	return attrs
+}
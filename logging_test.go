@@ -0,0 +1,57 @@
+package paypal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/crowdmob/paypal"
+)
+
+func TestLogging(t *testing.T) {
+	var logged bytes.Buffer
+	responses := []string{
+		"ACK=Success&CORRELATIONID=abc123&TOKEN=EC-123",
+		"ACK=Failure&CORRELATIONID=def456&L_ERRORCODE0=10417&L_SHORTMESSAGE0=Transaction+cannot+complete.",
+	}
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(responses[0]))
+		responses = responses[1:]
+	}, paypal.WithLogger(slog.New(slog.NewJSONHandler(&logged, nil))))
+
+	if _, err := client.SetExpressCheckoutDigitalGoodsContext(t.Context(), paypal.NewMoney(1000, "USD"), "https://example.com/ok", "https://example.com/cancel", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DoExpressCheckoutSaleContext(t.Context(), "EC-123", "PAYER", paypal.NewMoney(1000, "USD")); err == nil {
+		t.Fatal("Expected the payment to fail")
+	}
+
+	if strings.Contains(logged.String(), "pass") || strings.Contains(logged.String(), "sig") {
+		t.Errorf("Credentials were logged: %s", logged.String())
+	}
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(logged.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected one record per call, got %d", len(records))
+	}
+
+	if records[0]["level"] != "INFO" || records[0]["method"] != "SetExpressCheckout" || records[0]["correlation_id"] != "abc123" {
+		t.Errorf("Unexpected record for a successful call: %v", records[0])
+	}
+	if amounts, _ := records[0]["amounts"].(map[string]interface{}); amounts["PAYMENTREQUEST_0_AMT"] != "10.00" {
+		t.Errorf("Expected the amount to be logged, got %v", records[0])
+	}
+	if codes, _ := records[1]["error_codes"].([]interface{}); records[1]["level"] != "ERROR" || len(codes) != 1 || codes[0] != "10417" {
+		t.Errorf("Unexpected record for a failed call: %v", records[1])
+	}
+}
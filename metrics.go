@@ -0,0 +1,195 @@
+package paypal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives one observation per NVP call, covering all its retries.
+type Metrics interface {
+	ObserveCall(call CallMetrics)
+}
+
+// CallMetrics describes a finished NVP call.
+type CallMetrics struct {
+	Method     string
+	Ack        string // empty if PayPal didn't answer with an NVP response
+	ErrorCodes []string
+	Duration   time.Duration
+	Err        error
+}
+
+// WithMetrics reports every NVP call to metrics.
+func WithMetrics(metrics Metrics) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.metrics = metrics
+	}
+}
+
+func (pClient *PayPalClient) observeRequest(values url.Values, response *PayPalResponse, err error, duration time.Duration) {
+	if pClient.metrics == nil {
+		return
+	}
+
+	call := CallMetrics{Method: values.Get("METHOD"), Duration: duration, Err: err}
+	if response != nil {
+		call.Ack = response.Ack
+		call.ErrorCodes = errorCodes(response.Warnings)
+	}
+	var pError *PayPalError
+	if errors.As(err, &pError) {
+		call.ErrorCodes = errorCodes(pError.Errors)
+	}
+	pClient.metrics.ObserveCall(call)
+}
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency
+// histogram buckets used by NewMetricsCollector.
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// MetricsCollector is a Metrics that keeps counters and latency histograms
+// in memory and serves them in the Prometheus text exposition format:
+//
+//	metrics := paypal.NewMetricsCollector()
+//	client := paypal.NewClientWithOptions(username, password, signature, paypal.WithMetrics(metrics))
+//	http.Handle("/metrics/paypal", metrics)
+//
+// It exposes paypal_nvp_requests_total by method and ack,
+// paypal_nvp_errors_total by method and error code, and
+// paypal_nvp_request_duration_seconds by method. Calls that got no NVP
+// response are counted with the ack "none".
+type MetricsCollector struct {
+	buckets []float64
+
+	mu        sync.Mutex
+	requests  map[[2]string]uint64 // by method and ack
+	errors    map[[2]string]uint64 // by method and error code
+	latencies map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewMetricsCollector creates a collector with the given latency buckets in
+// seconds, or DefaultLatencyBuckets if there are none.
+func NewMetricsCollector(buckets ...float64) *MetricsCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &MetricsCollector{
+		buckets:   buckets,
+		requests:  make(map[[2]string]uint64),
+		errors:    make(map[[2]string]uint64),
+		latencies: make(map[string]*histogram),
+	}
+}
+
+func (m *MetricsCollector) ObserveCall(call CallMetrics) {
+	ack := call.Ack
+	if len(ack) == 0 {
+		ack = "none"
+	}
+	seconds := call.Duration.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[[2]string{call.Method, ack}]++
+	for _, code := range call.ErrorCodes {
+		m.errors[[2]string{call.Method, code}]++
+	}
+
+	h := m.latencies[call.Method]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latencies[call.Method] = h
+	}
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *MetricsCollector) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	m.mu.Lock()
+	b.WriteString("# HELP paypal_nvp_requests_total NVP calls by method and ack.\n")
+	b.WriteString("# TYPE paypal_nvp_requests_total counter\n")
+	for _, key := range sortedKeys(m.requests) {
+		fmt.Fprintf(&b, "paypal_nvp_requests_total{method=%s,ack=%s} %d\n", labelValue(key[0]), labelValue(key[1]), m.requests[key])
+	}
+
+	b.WriteString("# HELP paypal_nvp_errors_total Errors returned by PayPal by method and error code.\n")
+	b.WriteString("# TYPE paypal_nvp_errors_total counter\n")
+	for _, key := range sortedKeys(m.errors) {
+		fmt.Fprintf(&b, "paypal_nvp_errors_total{method=%s,error_code=%s} %d\n", labelValue(key[0]), labelValue(key[1]), m.errors[key])
+	}
+
+	b.WriteString("# HELP paypal_nvp_request_duration_seconds Latency of NVP calls, retries included.\n")
+	b.WriteString("# TYPE paypal_nvp_request_duration_seconds histogram\n")
+	methods := make([]string, 0, len(m.latencies))
+	for method := range m.latencies {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		h := m.latencies[method]
+		var cumulative uint64
+		for i, bound := range m.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "paypal_nvp_request_duration_seconds_bucket{method=%s,le=\"%s\"} %d\n", labelValue(method), strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "paypal_nvp_request_duration_seconds_bucket{method=%s,le=\"+Inf\"} %d\n", labelValue(method), h.count)
+		fmt.Fprintf(&b, "paypal_nvp_request_duration_seconds_sum{method=%s} %s\n", labelValue(method), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "paypal_nvp_request_duration_seconds_count{method=%s} %d\n", labelValue(method), h.count)
+	}
+	m.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func sortedKeys(counters map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelValue(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
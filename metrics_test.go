@@ -0,0 +1,51 @@
+package paypal_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal"
+)
+
+func TestMetricsCollector(t *testing.T) {
+	metrics := paypal.NewMetricsCollector()
+	responses := []string{
+		"ACK=Success&TOKEN=EC-123",
+		"ACK=Failure&L_ERRORCODE0=10417&L_SHORTMESSAGE0=Transaction+cannot+complete.",
+	}
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(responses[0]))
+		responses = responses[1:]
+	}, paypal.WithMetrics(metrics))
+
+	client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123")
+	client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123")
+	metrics.ObserveCall(paypal.CallMetrics{Method: `Odd"Method`, Duration: time.Minute})
+
+	server := httptest.NewServer(metrics)
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+
+	for _, line := range []string{
+		`paypal_nvp_requests_total{method="GetExpressCheckoutDetails",ack="Success"} 1`,
+		`paypal_nvp_requests_total{method="GetExpressCheckoutDetails",ack="Failure"} 1`,
+		`paypal_nvp_requests_total{method="Odd\"Method",ack="none"} 1`,
+		`paypal_nvp_errors_total{method="GetExpressCheckoutDetails",error_code="10417"} 1`,
+		`paypal_nvp_request_duration_seconds_bucket{method="GetExpressCheckoutDetails",le="+Inf"} 2`,
+		`paypal_nvp_request_duration_seconds_bucket{method="Odd\"Method",le="30"} 0`,
+		`paypal_nvp_request_duration_seconds_count{method="Odd\"Method"} 1`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("Expected %s in:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package paypal
+
+import (
+	"context"
+	"net/url"
+)
+
+// RoundTripper performs a single NVP call: it sends the request values,
+// credentials included, and returns PayPal's decoded response.
+type RoundTripper interface {
+	RoundTrip(ctx context.Context, values url.Values) (*PayPalResponse, error)
+}
+
+type RoundTripperFunc func(ctx context.Context, values url.Values) (*PayPalResponse, error)
+
+func (f RoundTripperFunc) RoundTrip(ctx context.Context, values url.Values) (*PayPalResponse, error) {
+	return f(ctx, values)
+}
+
+// Middleware wraps every attempt of every NVP call, retries included. It can
+// inspect or change the values before calling next, inspect the response and
+// error afterwards, time the call, or answer without calling next at all:
+//
+//	audit := func(next paypal.RoundTripper) paypal.RoundTripper {
+//		return paypal.RoundTripperFunc(func(ctx context.Context, values url.Values) (*paypal.PayPalResponse, error) {
+//			start := time.Now()
+//			response, err := next.RoundTrip(ctx, values)
+//			log.Printf("%s %v took %v: %v", values.Get("METHOD"), paypal.RedactedValues(values), time.Since(start), err)
+//			return response, err
+//		})
+//	}
+//
+// Values hold the API credentials; wrap them in RedactedValues before
+// logging them.
+type Middleware func(next RoundTripper) RoundTripper
+
+// WithMiddleware adds middleware to the client. The first one added is the
+// outermost, seeing requests first and responses last.
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.middleware = append(pClient.middleware, middleware...)
+	}
+}
+
+// roundTripper returns the client's middleware chain around sending the
+// request over HTTP.
+func (pClient *PayPalClient) roundTripper() RoundTripper {
+	var transport RoundTripper = RoundTripperFunc(pClient.performAttempt)
+	for i := len(pClient.middleware) - 1; i >= 0; i-- {
+		transport = pClient.middleware[i](transport)
+	}
+	return transport
+}
@@ -0,0 +1,74 @@
+package paypal_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal"
+)
+
+func recordingMiddleware(name string, calls *[]string) paypal.Middleware {
+	return func(next paypal.RoundTripper) paypal.RoundTripper {
+		return paypal.RoundTripperFunc(func(ctx context.Context, values url.Values) (*paypal.PayPalResponse, error) {
+			*calls = append(*calls, name+" "+values.Get("METHOD"))
+			response, err := next.RoundTrip(ctx, values)
+			*calls = append(*calls, name+" "+response.Ack)
+			return response, err
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("X-SIGNED") != "1" {
+			t.Errorf("Middleware didn't change the request: %v", r.Form)
+		}
+		w.Write([]byte("ACK=Success&TOKEN=EC-123"))
+	}, paypal.WithMiddleware(
+		recordingMiddleware("outer", &calls),
+		recordingMiddleware("inner", &calls),
+		func(next paypal.RoundTripper) paypal.RoundTripper {
+			return paypal.RoundTripperFunc(func(ctx context.Context, values url.Values) (*paypal.PayPalResponse, error) {
+				values.Set("X-SIGNED", "1")
+				return next.RoundTrip(ctx, values)
+			})
+		},
+	))
+
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"outer GetExpressCheckoutDetails", "inner GetExpressCheckoutDetails", "inner Success", "outer Success"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+}
+
+func TestMiddlewareFaultInjection(t *testing.T) {
+	var attempts, sent int
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		w.Write([]byte("ACK=Success&TOKEN=EC-123"))
+	}, paypal.WithRetryPolicy(&paypal.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}), paypal.WithMiddleware(
+		func(next paypal.RoundTripper) paypal.RoundTripper {
+			return paypal.RoundTripperFunc(func(ctx context.Context, values url.Values) (*paypal.PayPalResponse, error) {
+				if attempts++; attempts == 1 {
+					return nil, &paypal.StatusError{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}
+				}
+				return next.RoundTrip(ctx, values)
+			})
+		},
+	))
+
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+		t.Fatalf("Expected the injected failure to be retried, got %v", err)
+	}
+	if attempts != 2 || sent != 1 {
+		t.Errorf("Expected 2 attempts and 1 request sent, got %d and %d", attempts, sent)
+	}
+}
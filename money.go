@@ -0,0 +1,214 @@
+package paypal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrCurrencyMismatch = errors.New("paypal: currency mismatch")
+
+// Money is a fixed-point amount, stored in the minor units (e.g. cents) of
+// an ISO 4217 currency code.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+func NewMoney(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// ParseMoney parses a decimal amount as returned by PayPal ("10.00", "-3.5",
+// "1000") in the given currency. More decimal places than the currency allows
+// are rejected unless they are zero.
+func ParseMoney(s, currency string) (Money, error) {
+	str := strings.TrimSpace(s)
+	negative := strings.HasPrefix(str, "-")
+	if negative {
+		str = str[1:]
+	}
+
+	whole, frac, _ := strings.Cut(str, ".")
+	digits := currencyDigits(currency)
+	if len(frac) > digits {
+		if strings.Trim(frac[digits:], "0") != "" {
+			return Money{}, fmt.Errorf("paypal: amount %q has more than %d decimal places for %s", s, digits, currency)
+		}
+		frac = frac[:digits]
+	}
+	frac += strings.Repeat("0", digits-len(frac))
+
+	if len(whole) == 0 || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("paypal: invalid amount %q", s)
+	}
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("paypal: invalid amount %q", s)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// MoneyFromFloat rounds amount to the minor units of currency.
+//
+// Deprecated: floats cannot represent most decimal amounts exactly; use
+// NewMoney or ParseMoney instead.
+func MoneyFromFloat(amount float64, currency string) Money {
+	scale := math.Pow10(currencyDigits(currency))
+	return Money{Minor: int64(math.Round(amount * scale)), Currency: currency}
+}
+
+// exactMoneyFromFloat is MoneyFromFloat for the deprecated float wrappers,
+// which must not round an amount the caller meant to send as given.
+func exactMoneyFromFloat(field string, amount float64, currency string) (Money, error) {
+	scaled := amount * math.Pow10(currencyDigits(currency))
+	// a few ulps of slack for float error, e.g. 12.34*100 = 1233.9999999999998
+	ulp := math.Nextafter(math.Abs(scaled), math.Inf(1)) - math.Abs(scaled)
+	if math.Abs(scaled-math.Round(scaled)) > 8*ulp {
+		value := strconv.FormatFloat(amount, 'f', -1, 64) + " " + currency
+		return Money{}, &ValidationError{Field: field, Value: value, Reason: fmt.Sprintf("amount has more than %d decimal places", currencyDigits(currency))}
+	}
+	return MoneyFromFloat(amount, currency), nil
+}
+
+// Float64 returns the amount in major units.
+//
+// Deprecated: only meant for callers still using float64 amounts.
+func (m Money) Float64() float64 {
+	return float64(m.Minor) / math.Pow10(currencyDigits(m.Currency))
+}
+
+// Decimal formats the amount the way NVP expects it, with as many decimal
+// places as the currency uses ("10.00" for USD, "1000" for JPY).
+func (m Money) Decimal() string {
+	digits := currencyDigits(m.Currency)
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	if digits == 0 {
+		return sign + strconv.FormatInt(minor, 10)
+	}
+
+	scale := int64(math.Pow10(digits))
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/scale, digits, minor%scale)
+}
+
+func (m Money) String() string {
+	if len(m.Currency) == 0 {
+		return m.Decimal()
+	}
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Minor: m.Minor + o.Minor, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Minor: m.Minor - o.Minor, Currency: m.Currency}, nil
+}
+
+func (m Money) Mul(n int64) Money {
+	return Money{Minor: m.Minor * n, Currency: m.Currency}
+}
+
+// Cmp returns -1, 0 or +1 depending on whether m is less than, equal to or
+// greater than o.
+func (m Money) Cmp(o Money) (int, error) {
+	if m.Currency != o.Currency {
+		return 0, ErrCurrencyMismatch
+	}
+	switch {
+	case m.Minor < o.Minor:
+		return -1, nil
+	case m.Minor > o.Minor:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (m Money) Equal(o Money) bool {
+	return m.Currency == o.Currency && m.Minor == o.Minor
+}
+
+// SumDigitalGoods returns the total of Amount * Quantity over goods.
+func SumDigitalGoods(goods []PayPalDigitalGood) (sum Money, err error) {
+	for i, dg := range goods {
+		if i == 0 {
+			sum.Currency = dg.Amount.Currency
+		}
+		if sum, err = sum.Add(dg.Amount.Mul(int64(dg.Quantity))); err != nil {
+			return Money{}, err
+		}
+	}
+	return
+}
+
+// sameCurrency returns the currency shared by amounts, ignoring amounts
+// without one, or ErrCurrencyMismatch.
+func sameCurrency(amounts ...Money) (string, error) {
+	var currency string
+	for _, m := range amounts {
+		if len(m.Currency) == 0 {
+			continue
+		}
+		if len(currency) != 0 && m.Currency != currency {
+			return "", ErrCurrencyMismatch
+		}
+		currency = m.Currency
+	}
+	return currency, nil
+}
+
+// MarshalNVPAmount and UnmarshalNVPAmount let Money be used in structs
+// encoded with the nvp package. Negative amounts are marshaled as they are;
+// whether a field may be negative is up to the request building it.
+func (m Money) MarshalNVPAmount() (string, string, error) {
+	if err := validateCurrency("amount", m); err != nil {
+		return "", "", err
+	}
+	return m.Decimal(), m.Currency, nil
+}
+
+func (m *Money) UnmarshalNVPAmount(amount, currency string) error {
+	parsed, err := ParseMoney(amount, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
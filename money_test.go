@@ -0,0 +1,98 @@
+package paypal_test
+
+import (
+	"testing"
+
+	"github.com/crowdmob/paypal"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		in       string
+		currency string
+		minor    int64
+		decimal  string
+	}{
+		{"10.00", "USD", 1000, "10.00"},
+		{"10.5", "USD", 1050, "10.50"},
+		{"7", "EUR", 700, "7.00"},
+		{"-3.05", "USD", -305, "-3.05"},
+		{"1000", "JPY", 1000, "1000"},
+		{"1000.00", "JPY", 1000, "1000"},
+	}
+
+	for _, c := range cases {
+		m, err := paypal.ParseMoney(c.in, c.currency)
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %q) returned error: %v", c.in, c.currency, err)
+			continue
+		}
+		if m.Minor != c.minor || m.Currency != c.currency {
+			t.Errorf("ParseMoney(%q, %q) = %#v, want %d", c.in, c.currency, m, c.minor)
+		}
+		if m.Decimal() != c.decimal {
+			t.Errorf("ParseMoney(%q, %q).Decimal() = %q, want %q", c.in, c.currency, m.Decimal(), c.decimal)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "1.234", "10.5", "1e3"} {
+		currency := "USD"
+		if in == "10.5" {
+			currency = "JPY"
+		}
+		if _, err := paypal.ParseMoney(in, currency); err == nil {
+			t.Errorf("ParseMoney(%q, %q) should have failed", in, currency)
+		}
+	}
+}
+
+func TestSumDigitalGoods(t *testing.T) {
+	goods := []paypal.PayPalDigitalGood{
+		{Name: "A", Amount: paypal.NewMoney(10, "USD"), Quantity: 3},
+		{Name: "B", Amount: paypal.NewMoney(20, "USD"), Quantity: 1},
+	}
+
+	sum, err := paypal.SumDigitalGoods(goods)
+	if err != nil {
+		t.Fatalf("SumDigitalGoods returned error: %v", err)
+	}
+	if !sum.Equal(paypal.NewMoney(50, "USD")) {
+		t.Errorf("SumDigitalGoods = %v, want 0.50 USD", sum)
+	}
+
+	goods = append(goods, paypal.PayPalDigitalGood{Name: "C", Amount: paypal.NewMoney(100, "EUR"), Quantity: 1})
+	if _, err := paypal.SumDigitalGoods(goods); err != paypal.ErrCurrencyMismatch {
+		t.Errorf("SumDigitalGoods with mixed currencies returned %v, want ErrCurrencyMismatch", err)
+	}
+}
+
+func TestValidateAmount(t *testing.T) {
+	valid := []paypal.Money{
+		paypal.NewMoney(0, "USD"),
+		paypal.NewMoney(1000000, "USD"),
+		paypal.NewMoney(1000000, "JPY"),
+		paypal.NewMoney(500, ""),
+	}
+	for _, m := range valid {
+		if err := paypal.ValidateAmount("AMT", m); err != nil {
+			t.Errorf("ValidateAmount(%v) returned error: %v", m, err)
+		}
+	}
+
+	invalid := []paypal.Money{
+		paypal.NewMoney(-1, "USD"),
+		paypal.NewMoney(1000001, "USD"),
+		paypal.NewMoney(1000001, "JPY"),
+		paypal.NewMoney(100, "XXX"),
+	}
+	for _, m := range invalid {
+		err := paypal.ValidateAmount("AMT", m)
+		if _, ok := err.(*paypal.ValidationError); !ok {
+			t.Errorf("ValidateAmount(%v) = %v, want a *ValidationError", m, err)
+		}
+	}
+
+	if d := paypal.NewMoney(1500, "JPY").Decimal(); d != "1500" {
+		t.Errorf("JPY amounts should be formatted without decimals, got %q", d)
+	}
+}
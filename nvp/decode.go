@@ -0,0 +1,197 @@
+package nvp
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type decoder struct {
+	values url.Values
+	errors []*FieldError
+}
+
+func (d *decoder) lookup(key string) (string, bool) {
+	values, ok := d.values[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+func (d *decoder) decodeStruct(v reflect.Value, s scope) error {
+	fields, err := typeFields(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		fv := v.Field(f.index)
+
+		switch f.kind {
+		case valueField:
+			if raw, ok := d.lookup(s.key(f.name)); ok {
+				d.decodeValue(fv, s, f, raw)
+			}
+		case structField:
+			if err := d.decodeStruct(fv, s.nested(f.name)); err != nil {
+				return err
+			}
+		case sectionField, listField:
+			elemType := fv.Type().Elem()
+			elems := reflect.MakeSlice(fv.Type(), 0, 0)
+			for n := 0; ; n++ {
+				elem := reflect.New(elemType).Elem()
+				if isValue(elemType) {
+					// L_NAMEn, with its currency in L_CURRENCYCODEn
+					elemScope, err := s.listElem("", n)
+					if err != nil {
+						return err
+					}
+					raw, ok := d.lookup(elemScope.key(f.name))
+					if !ok {
+						break
+					}
+					d.decodeValue(elem, elemScope, field{name: f.name, currency: f.currency, listValue: true}, raw)
+				} else {
+					elemScope, err := s.elemScope(f, n)
+					if err != nil {
+						return err
+					}
+					if !d.present(elemType, elemScope) {
+						break
+					}
+					if err := d.decodeStruct(elem, elemScope); err != nil {
+						return err
+					}
+				}
+				elems = reflect.Append(elems, elem)
+			}
+			if elems.Len() != 0 {
+				fv.Set(elems)
+			}
+		case commaField:
+			raw, ok := d.lookup(s.key(f.name))
+			if !ok {
+				continue
+			}
+			elems := reflect.MakeSlice(fv.Type(), 0, 0)
+			for _, elem := range strings.Split(raw, ",") {
+				if elem = strings.TrimSpace(elem); len(elem) != 0 {
+					elems = reflect.Append(elems, reflect.ValueOf(elem).Convert(fv.Type().Elem()))
+				}
+			}
+			fv.Set(elems)
+		}
+	}
+	return nil
+}
+
+// present reports whether values hold any field of a value of type t in
+// scope s, i.e. whether a section or list has an element at that index.
+func (d *decoder) present(t reflect.Type, s scope) bool {
+	fields, err := typeFields(t)
+	if err != nil {
+		return false
+	}
+	for _, f := range fields {
+		ft := t.Field(f.index).Type
+		switch f.kind {
+		case valueField, commaField:
+			if _, ok := d.lookup(s.key(f.name)); ok {
+				return true
+			}
+		case structField:
+			if d.present(ft, s.nested(f.name)) {
+				return true
+			}
+		case listField:
+			if isValue(ft.Elem()) {
+				if elemScope, err := s.listElem("", 0); err == nil {
+					if _, ok := d.lookup(elemScope.key(f.name)); ok {
+						return true
+					}
+				}
+				continue
+			}
+			fallthrough
+		case sectionField:
+			if elemScope, err := s.elemScope(f, 0); err == nil && d.present(ft.Elem(), elemScope) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeValue sets v from raw, recording a FieldError and zeroing v if raw
+// is malformed. Empty values decode as the zero value.
+func (d *decoder) decodeValue(v reflect.Value, s scope, f field, raw string) {
+	key := s.key(f.name)
+	if len(raw) == 0 && v.Kind() != reflect.String {
+		v.Set(reflect.Zero(v.Type()))
+		return
+	}
+
+	var err error
+	if u, ok := asInterface(v, amountUnmarshalerType).(AmountUnmarshaler); ok {
+		var currency string
+		if len(f.currency) != 0 {
+			for _, currencyKey := range s.currencyKeys(f.currency) {
+				if currency, _ = d.lookup(currencyKey); len(currency) != 0 {
+					break
+				}
+			}
+		}
+		err = u.UnmarshalNVPAmount(raw, currency)
+	} else if u, ok := asInterface(v, unmarshalerType).(Unmarshaler); ok {
+		err = u.UnmarshalNVP(raw)
+	} else if v.Type() == timeType {
+		var t time.Time
+		if t, err = time.Parse(time.RFC3339, raw); err == nil {
+			v.Set(reflect.ValueOf(t))
+		}
+	} else {
+		err = decodeBasic(v, raw)
+	}
+
+	if err != nil {
+		v.Set(reflect.Zero(v.Type()))
+		d.errors = append(d.errors, &FieldError{Key: key, Value: raw, Err: err})
+	}
+}
+
+func decodeBasic(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("not a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err.(*strconv.NumError).Err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err.(*strconv.NumError).Err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err.(*strconv.NumError).Err
+		}
+		v.SetFloat(f)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package nvp
+
+import (
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func encodeStruct(values url.Values, v reflect.Value, s scope) error {
+	fields, err := typeFields(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		switch f.kind {
+		case valueField:
+			if err := encodeValue(values, fv, s, f); err != nil {
+				return err
+			}
+		case structField:
+			if err := encodeStruct(values, fv, s.nested(f.name)); err != nil {
+				return err
+			}
+		case sectionField:
+			for n := 0; n < fv.Len(); n++ {
+				elemScope, err := s.sectionElem(f.name, n)
+				if err != nil {
+					return err
+				}
+				if err := encodeStruct(values, fv.Index(n), elemScope); err != nil {
+					return err
+				}
+			}
+		case listField:
+			for n := 0; n < fv.Len(); n++ {
+				if isValue(fv.Type().Elem()) {
+					// L_NAMEn, with its currency in L_CURRENCYCODEn
+					elemScope, err := s.listElem("", n)
+					if err == nil {
+						err = encodeValue(values, fv.Index(n), elemScope, field{name: f.name, currency: f.currency, listValue: true})
+					}
+					if err != nil {
+						return err
+					}
+					continue
+				}
+
+				elemScope, err := s.listElem(f.name, n)
+				if err != nil {
+					return err
+				}
+				if err := encodeStruct(values, fv.Index(n), elemScope); err != nil {
+					return err
+				}
+			}
+		case commaField:
+			elems := make([]string, fv.Len())
+			for i := range elems {
+				elems[i] = fv.Index(i).String()
+			}
+			values.Set(s.key(f.name), strings.Join(elems, ","))
+		}
+	}
+	return nil
+}
+
+func encodeValue(values url.Values, v reflect.Value, s scope, f field) error {
+	key := s.key(f.name)
+
+	if m, ok := asInterface(v, amountMarshalerType).(AmountMarshaler); ok {
+		amount, currency, err := m.MarshalNVPAmount()
+		if err != nil {
+			return &FieldError{Key: key, Err: err}
+		}
+		values.Set(key, amount)
+		if len(f.currency) != 0 && len(currency) != 0 {
+			keys := s.currencyKeys(f.currency)
+			if f.listValue {
+				values.Set(keys[0], currency)
+			} else if len(values.Get(keys[0])) == 0 && len(values.Get(keys[1])) == 0 {
+				values.Set(keys[1], currency)
+			}
+		}
+		return nil
+	}
+	if m, ok := asInterface(v, marshalerType).(Marshaler); ok {
+		value, err := m.MarshalNVP()
+		if err != nil {
+			return &FieldError{Key: key, Err: err}
+		}
+		values.Set(key, value)
+		return nil
+	}
+	if v.Type() == timeType {
+		values.Set(key, v.Interface().(time.Time).UTC().Format(TIME_FORMAT))
+		return nil
+	}
+
+	var value string
+	switch v.Kind() {
+	case reflect.String:
+		value = v.String()
+	case reflect.Bool:
+		value = "0"
+		if v.Bool() {
+			value = "1"
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		value = strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+	values.Set(key, value)
+	return nil
+}
+
+// asInterface returns v, or a pointer to v if only the pointer implements
+// iface, as an interface{}; nil if neither does.
+func asInterface(v reflect.Value, iface reflect.Type) interface{} {
+	if v.Type().Implements(iface) {
+		return v.Interface()
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(iface) {
+		return v.Addr().Interface()
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+// Package nvp converts structs to and from the name-value pairs of PayPal's
+// NVP API, driven by struct tags:
+//
+//	type Item struct {
+//		Name     string `nvp:"NAME"`
+//		Amount   Money  `nvp:"AMT,currency=CURRENCYCODE"`
+//		Quantity int    `nvp:"QTY"`
+//	}
+//
+//	type PaymentRequest struct {
+//		Amount   Money     `nvp:"AMT,currency=CURRENCYCODE"`
+//		Currency string    `nvp:"CURRENCYCODE"`
+//		ShipTo   Address   `nvp:"SHIPTO"`
+//		Items    []Item    `nvp:",list"`
+//		Created  time.Time `nvp:"ORDERTIME,omitempty"`
+//	}
+//
+//	type Request struct {
+//		Token           string           `nvp:"TOKEN"`
+//		NoShipping      bool             `nvp:"NOSHIPPING"`
+//		PaymentRequests []PaymentRequest `nvp:"PAYMENTREQUEST,section"`
+//	}
+//
+// encodes as TOKEN, NOSHIPPING, PAYMENTREQUEST_n_AMT,
+// PAYMENTREQUEST_n_SHIPTONAME, L_PAYMENTREQUEST_n_NAMEm and so on.
+//
+// The tag is the name of the field, which defaults to the upper-cased Go
+// field name, followed by options:
+//
+//	omitempty    don't encode the zero value
+//	inline       a struct whose fields are encoded without a prefix
+//	section      a slice of structs encoded as NAME_n_FIELD, like PAYMENTREQUEST_n_
+//	list         a slice encoded as L_NAMEn, or L_NAMEFIELDn for structs
+//	comma        a slice of strings joined by commas in a single value
+//	currency=KEY the key of the currency code passed to AmountMarshaler and
+//	             AmountUnmarshaler, looked up next to the amount and then in
+//	             its section. Amounts in lists of values, like L_AMTn, have
+//	             their own L_KEYn; other amounts set KEY in their section.
+//
+// A struct field without the section, list or inline option prefixes the
+// names of its fields with its own name; embedded structs are inlined. A tag
+// of "-" skips the field.
+//
+// Strings, bools (encoded as 1 and 0), integers, floats and time.Time
+// (encoded in TIME_FORMAT, decoded from any RFC 3339 time) are supported, as well as types
+// implementing Marshaler, Unmarshaler, AmountMarshaler or AmountUnmarshaler.
+package nvp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TIME_FORMAT is the format of PayPal timestamps such as TIMESTAMP and
+// ORDERTIME, and the one times are encoded in.
+const TIME_FORMAT = "2006-01-02T15:04:05Z"
+
+// Marshaler is implemented by types that encode themselves as a single
+// value.
+type Marshaler interface {
+	MarshalNVP() (string, error)
+}
+
+// Unmarshaler is implemented by types that decode themselves from a single
+// value.
+type Unmarshaler interface {
+	UnmarshalNVP(value string) error
+}
+
+// AmountMarshaler is implemented by amount types that also carry their
+// currency, which is encoded into the field named by the currency option
+// unless it is already set.
+type AmountMarshaler interface {
+	MarshalNVPAmount() (amount, currency string, err error)
+}
+
+// AmountUnmarshaler is implemented by amount types that need the currency,
+// named by the currency option, to decode an amount.
+type AmountUnmarshaler interface {
+	UnmarshalNVPAmount(amount, currency string) error
+}
+
+// FieldError is a value that couldn't be decoded into its field.
+type FieldError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("nvp: invalid %s %q: %v", e.Key, e.Value, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError lists every malformed field found by UnmarshalStrict.
+type DecodeError struct {
+	Errors []*FieldError
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%v (and %d more)", e.Errors[0], len(e.Errors)-1)
+}
+
+// Marshal encodes the struct v points to, or v itself, as NVP values.
+func Marshal(v interface{}) (url.Values, error) {
+	values := url.Values{}
+	if err := MarshalInto(values, v); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// MarshalInto encodes v like Marshal, adding the fields to values.
+func MarshalInto(values url.Values, v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("nvp: can't marshal %T, only structs", v)
+	}
+	if !rv.CanAddr() {
+		// make pointer-receiver marshalers of the fields callable
+		addressable := reflect.New(rv.Type()).Elem()
+		addressable.Set(rv)
+		rv = addressable
+	}
+	return encodeStruct(values, rv, scope{})
+}
+
+// Unmarshal decodes values into the struct v points to. Fields whose values
+// are missing are left alone, and fields whose values are malformed are set
+// to their zero value.
+func Unmarshal(values url.Values, v interface{}) error {
+	return unmarshal(values, v, false)
+}
+
+// UnmarshalStrict decodes values like Unmarshal, but returns a *DecodeError
+// listing the malformed fields. The other fields are still decoded.
+func UnmarshalStrict(values url.Values, v interface{}) error {
+	return unmarshal(values, v, true)
+}
+
+func unmarshal(values url.Values, v interface{}, strict bool) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("nvp: can't unmarshal into %T, only pointers to structs", v)
+	}
+
+	d := &decoder{values: values}
+	if err := d.decodeStruct(rv.Elem(), scope{}); err != nil {
+		return err
+	}
+	if strict && len(d.errors) != 0 {
+		return &DecodeError{Errors: d.errors}
+	}
+	return nil
+}
+
+// scope builds the keys of the fields of a struct nested in sections, lists
+// and other structs: lead + section + prefix + NAME + suffix, as in
+// L_PAYMENTREQUEST_0_NAME1.
+type scope struct {
+	lead    string // "L_" inside lists
+	section string // e.g. "PAYMENTREQUEST_0_"
+	prefix  string // names of enclosing structs and lists
+	suffix  string // index inside lists
+}
+
+func (s scope) key(name string) string {
+	return s.lead + s.section + s.prefix + name + s.suffix
+}
+
+// currencyKeys returns the keys the currency option name is looked up at:
+// next to the amount, then in its section.
+func (s scope) currencyKeys(name string) []string {
+	return []string{s.key(name), s.section + name}
+}
+
+func (s scope) nested(name string) scope {
+	s.prefix += name
+	return s
+}
+
+func (s scope) sectionElem(name string, n int) (scope, error) {
+	if len(s.lead) != 0 {
+		return s, errors.New("nvp: sections can't be nested in lists")
+	}
+	return scope{section: s.section + s.prefix + name + "_" + strconv.Itoa(n) + "_"}, nil
+}
+
+func (s scope) listElem(name string, n int) (scope, error) {
+	if len(s.lead) != 0 {
+		return s, errors.New("nvp: lists can't be nested in lists")
+	}
+	return scope{lead: "L_", section: s.section, prefix: s.prefix + name, suffix: strconv.Itoa(n)}, nil
+}
+
+// elemScope returns the scope of the struct at index n of a section or list.
+func (s scope) elemScope(f field, n int) (scope, error) {
+	if f.kind == sectionField {
+		return s.sectionElem(f.name, n)
+	}
+	return s.listElem(f.name, n)
+}
+
+type fieldKind int
+
+const (
+	valueField fieldKind = iota
+	structField
+	sectionField
+	listField
+	commaField
+)
+
+type field struct {
+	index     int
+	name      string
+	kind      fieldKind
+	omitEmpty bool
+	currency  string
+	listValue bool // an element of a list of values, whose currency is in the list as well
+}
+
+var fieldCache sync.Map // reflect.Type to []field
+
+var (
+	marshalerType         = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType       = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	amountMarshalerType   = reflect.TypeOf((*AmountMarshaler)(nil)).Elem()
+	amountUnmarshalerType = reflect.TypeOf((*AmountUnmarshaler)(nil)).Elem()
+	timeType              = reflect.TypeOf(time.Time{})
+)
+
+// isValue reports whether t is encoded as a single value rather than as a
+// struct of fields.
+func isValue(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	for _, i := range []reflect.Type{marshalerType, unmarshalerType, amountMarshalerType, amountUnmarshalerType} {
+		if t.Implements(i) || reflect.PtrTo(t).Implements(i) {
+			return true
+		}
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func typeFields(t reflect.Type) ([]field, error) {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]field), nil
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("nvp")
+		if tag == "-" || (!sf.IsExported() && !sf.Anonymous) {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		f := field{index: i, name: name}
+		if len(tag) == 0 {
+			f.name = strings.ToUpper(sf.Name)
+			if sf.Anonymous {
+				f.name = ""
+			}
+		}
+
+		var section, list, comma bool
+		for _, option := range strings.Split(options, ",") {
+			switch {
+			case option == "":
+			case option == "omitempty":
+				f.omitEmpty = true
+			case option == "inline":
+				f.name = ""
+			case option == "section":
+				section = true
+			case option == "list":
+				list = true
+			case option == "comma":
+				comma = true
+			case strings.HasPrefix(option, "currency="):
+				f.currency = strings.TrimPrefix(option, "currency=")
+			default:
+				return nil, fmt.Errorf("nvp: unknown option %q on %s.%s", option, t, sf.Name)
+			}
+		}
+
+		ft := sf.Type
+		switch {
+		case section:
+			if ft.Kind() != reflect.Slice || ft.Elem().Kind() != reflect.Struct || isValue(ft.Elem()) {
+				return nil, fmt.Errorf("nvp: section %s.%s must be a slice of structs", t, sf.Name)
+			}
+			f.kind = sectionField
+		case list:
+			if ft.Kind() != reflect.Slice || (ft.Elem().Kind() != reflect.Struct && !isValue(ft.Elem())) {
+				return nil, fmt.Errorf("nvp: list %s.%s must be a slice", t, sf.Name)
+			}
+			f.kind = listField
+		case comma:
+			if ft.Kind() != reflect.Slice || ft.Elem().Kind() != reflect.String {
+				return nil, fmt.Errorf("nvp: comma separated %s.%s must be a slice of strings", t, sf.Name)
+			}
+			f.kind = commaField
+		case isValue(ft):
+			f.kind = valueField
+		case ft.Kind() == reflect.Struct:
+			f.kind = structField
+		default:
+			return nil, fmt.Errorf("nvp: unsupported type %s of %s.%s", ft, t, sf.Name)
+		}
+		// embedded unexported structs only contribute their exported fields
+		if !sf.IsExported() && f.kind != structField {
+			continue
+		}
+		fields = append(fields, f)
+	}
+
+	fieldCache.Store(t, fields)
+	return fields, nil
+}
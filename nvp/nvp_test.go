@@ -0,0 +1,203 @@
+package nvp_test
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal/nvp"
+)
+
+// cents is an amount in the minor units of its currency, which is always
+// USD in these tests.
+type cents struct {
+	Minor    int64
+	Currency string
+}
+
+func (c cents) MarshalNVPAmount() (string, string, error) {
+	return fmt.Sprintf("%d.%02d", c.Minor/100, c.Minor%100), c.Currency, nil
+}
+
+func (c *cents) UnmarshalNVPAmount(amount, currency string) error {
+	whole, frac, _ := strings.Cut(amount, ".")
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil || len(frac) != 2 {
+		return errors.New("not an amount")
+	}
+	*c = cents{Minor: minor, Currency: currency}
+	return nil
+}
+
+type item struct {
+	Name     string `nvp:"NAME"`
+	Amount   cents  `nvp:"AMT,currency=CURRENCYCODE"`
+	Quantity int    `nvp:"QTY"`
+}
+
+type address struct {
+	Name string
+	City string
+}
+
+type paymentRequest struct {
+	Amount    cents     `nvp:"AMT,currency=CURRENCYCODE"`
+	ShipTo    address   `nvp:"SHIPTO"`
+	Items     []item    `nvp:",list"`
+	OrderTime time.Time `nvp:"ORDERTIME,omitempty"`
+}
+
+type common struct {
+	Token string
+}
+
+type request struct {
+	common
+	NoShipping      bool             `nvp:"NOSHIPPING"`
+	BillingTypes    []string         `nvp:"BILLINGTYPE,list"`
+	Eligibility     []string         `nvp:"PROTECTIONELIGIBILITYTYPE,comma,omitempty"`
+	PaymentRequests []paymentRequest `nvp:"PAYMENTREQUEST,section"`
+	Ignored         string           `nvp:"-"`
+}
+
+var orderTime = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+var encoded = url.Values{
+	"TOKEN":                         {"EC-123"},
+	"NOSHIPPING":                    {"1"},
+	"L_BILLINGTYPE0":                {"MerchantInitiatedBilling"},
+	"PAYMENTREQUEST_0_AMT":          {"12.50"},
+	"PAYMENTREQUEST_0_CURRENCYCODE": {"USD"},
+	"PAYMENTREQUEST_0_SHIPTONAME":   {"Jane Doe"},
+	"PAYMENTREQUEST_0_SHIPTOCITY":   {"San Jose"},
+	"L_PAYMENTREQUEST_0_NAME0":      {"Song"},
+	"L_PAYMENTREQUEST_0_AMT0":       {"2.50"},
+	"L_PAYMENTREQUEST_0_QTY0":       {"1"},
+	"L_PAYMENTREQUEST_0_NAME1":      {"Album"},
+	"L_PAYMENTREQUEST_0_AMT1":       {"10.00"},
+	"L_PAYMENTREQUEST_0_QTY1":       {"1"},
+	"PAYMENTREQUEST_0_ORDERTIME":    {"2024-03-01T12:30:00Z"},
+	"PAYMENTREQUEST_1_AMT":          {"5.00"},
+	"PAYMENTREQUEST_1_CURRENCYCODE": {"USD"},
+	"PAYMENTREQUEST_1_SHIPTONAME":   {""},
+	"PAYMENTREQUEST_1_SHIPTOCITY":   {""},
+}
+
+var decoded = request{
+	common:       common{Token: "EC-123"},
+	NoShipping:   true,
+	BillingTypes: []string{"MerchantInitiatedBilling"},
+	PaymentRequests: []paymentRequest{
+		{
+			Amount: cents{1250, "USD"},
+			ShipTo: address{Name: "Jane Doe", City: "San Jose"},
+			Items: []item{
+				{Name: "Song", Amount: cents{250, "USD"}, Quantity: 1},
+				{Name: "Album", Amount: cents{1000, "USD"}, Quantity: 1},
+			},
+			OrderTime: orderTime,
+		},
+		{Amount: cents{500, "USD"}},
+	},
+}
+
+func TestMarshal(t *testing.T) {
+	values, err := nvp.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, encoded) {
+		t.Errorf("Expected\n%v\ngot\n%v", encoded, values)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var r request
+	if err := nvp.UnmarshalStrict(encoded, &r); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, decoded) {
+		t.Errorf("Expected\n%+v\ngot\n%+v", decoded, r)
+	}
+}
+
+func TestUnmarshalMalformed(t *testing.T) {
+	values := url.Values{
+		"NOSHIPPING":                    {"maybe"},
+		"PROTECTIONELIGIBILITYTYPE":     {"ItemNotReceivedEligible,UnauthorizedPaymentEligible"},
+		"PAYMENTREQUEST_0_AMT":          {"12.5.0"},
+		"PAYMENTREQUEST_0_CURRENCYCODE": {"USD"},
+		"L_PAYMENTREQUEST_0_NAME0":      {"Song"},
+		"L_PAYMENTREQUEST_0_QTY0":       {"one"},
+	}
+
+	r := request{NoShipping: true}
+	if err := nvp.Unmarshal(values, &r); err != nil {
+		t.Fatalf("Expected malformed fields to be zeroed, got %v", err)
+	}
+	if r.NoShipping || r.PaymentRequests[0].Amount != (cents{}) || r.PaymentRequests[0].Items[0].Name != "Song" || len(r.Eligibility) != 2 {
+		t.Errorf("Unexpected result %+v", r)
+	}
+
+	err := nvp.UnmarshalStrict(values, &r)
+	var decodeError *nvp.DecodeError
+	if !errors.As(err, &decodeError) || len(decodeError.Errors) != 3 {
+		t.Fatalf("Expected 3 malformed fields, got %v", err)
+	}
+	keys := make([]string, len(decodeError.Errors))
+	for i, fieldError := range decodeError.Errors {
+		keys[i] = fieldError.Key
+	}
+	if strings.Join(keys, " ") != "NOSHIPPING PAYMENTREQUEST_0_AMT L_PAYMENTREQUEST_0_QTY0" {
+		t.Errorf("Unexpected malformed fields %v", keys)
+	}
+}
+
+func TestUnsupportedFields(t *testing.T) {
+	type listItem struct {
+		Sections []paymentRequest `nvp:"PAYMENTREQUEST,section"`
+	}
+	nestedSection := struct {
+		Items []listItem `nvp:",list"`
+	}{Items: []listItem{{Sections: make([]paymentRequest, 1)}}}
+	if _, err := nvp.Marshal(nestedSection); err == nil {
+		t.Error("Expected sections in lists to be rejected")
+	}
+
+	var unsupported struct {
+		Values map[string]string
+	}
+	if err := nvp.Unmarshal(url.Values{}, &unsupported); err == nil {
+		t.Error("Expected maps to be rejected")
+	}
+}
+
+func TestListOfAmounts(t *testing.T) {
+	type balance struct {
+		Amounts []cents `nvp:"AMT,list,currency=CURRENCYCODE"`
+	}
+	values := url.Values{
+		"L_AMT0":          {"12.50"},
+		"L_CURRENCYCODE0": {"USD"},
+		"L_AMT1":          {"3.00"},
+		"L_CURRENCYCODE1": {"EUR"},
+	}
+
+	var b balance
+	if err := nvp.UnmarshalStrict(values, &b); err != nil {
+		t.Fatal(err)
+	}
+	expected := []cents{{1250, "USD"}, {300, "EUR"}}
+	if !reflect.DeepEqual(b.Amounts, expected) {
+		t.Errorf("Expected %v, got %v", expected, b.Amounts)
+	}
+
+	if encoded, err := nvp.Marshal(b); err != nil || !reflect.DeepEqual(encoded, values) {
+		t.Errorf("Expected %v, got %v (%v)", values, encoded, err)
+	}
+}
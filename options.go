@@ -0,0 +1,120 @@
+package paypal
+
+import (
+	"net/http"
+	"time"
+)
+
+type ClientOption func(*PayPalClient)
+
+// NewClientWithOptions creates a client for the production endpoints, unless
+// told otherwise by options:
+//
+//	client := paypal.NewClientWithOptions(username, password, signature,
+//		paypal.WithSandbox(true),
+//		paypal.WithTimeout(10*time.Second),
+//	)
+func NewClientWithOptions(username, password, signature string, options ...ClientOption) *PayPalClient {
+	pClient := &PayPalClient{
+		credentials: StaticCredentials{Username: username, Password: password, Signature: signature},
+		client:      new(http.Client),
+		version:     NVP_VERSION,
+	}
+
+	for _, option := range options {
+		option(pClient)
+	}
+
+	if len(pClient.endpoint) == 0 {
+		switch {
+		case pClient.certificate != nil && pClient.usesSandbox:
+			pClient.endpoint = NVP_CERTIFICATE_SANDBOX_URL
+		case pClient.certificate != nil:
+			pClient.endpoint = NVP_CERTIFICATE_PRODUCTION_URL
+		case pClient.usesSandbox:
+			pClient.endpoint = NVP_SANDBOX_URL
+		default:
+			pClient.endpoint = NVP_PRODUCTION_URL
+		}
+	}
+	if len(pClient.checkoutUrl) == 0 {
+		pClient.checkoutUrl = CHECKOUT_PRODUCTION_URL
+		if pClient.usesSandbox {
+			pClient.checkoutUrl = CHECKOUT_SANDBOX_URL
+		}
+	}
+
+	return pClient
+}
+
+// WithSandbox selects the sandbox NVP and checkout URLs, unless they are set
+// explicitly with WithEndpoint and WithCheckoutUrl.
+func WithSandbox(usesSandbox bool) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.usesSandbox = usesSandbox
+	}
+}
+
+// WithEndpoint sets the URL NVP requests are posted to, e.g. a regional
+// endpoint or a local stand-in server in tests.
+func WithEndpoint(nvpUrl string) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.endpoint = nvpUrl
+	}
+}
+
+// WithCheckoutUrl sets the URL GetCheckoutUrl redirects buyers to.
+func WithCheckoutUrl(checkoutUrl string) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.checkoutUrl = checkoutUrl
+	}
+}
+
+// WithAPIVersion overrides the NVP VERSION sent with every request, which
+// defaults to NVP_VERSION.
+func WithAPIVersion(version string) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.version = version
+	}
+}
+
+func WithUserAgent(userAgent string) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.userAgent = userAgent
+	}
+}
+
+// WithTimeout bounds every call, including its retries. It is applied to the
+// request context rather than the http.Client, so a shared client isn't
+// modified.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.timeout = timeout
+	}
+}
+
+// WithCredentialProvider makes the client ask provider for its credentials on
+// every request, instead of using the ones it was created with.
+func WithCredentialProvider(provider CredentialProvider) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.credentials = provider
+	}
+}
+
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.client = client
+	}
+}
+
+func WithRetryPolicy(policy *RetryPolicy) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.retryPolicy = policy
+	}
+}
+
+func WithIdempotencyStore(store IdempotencyStore) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.idempotencyStore = store
+	}
+}
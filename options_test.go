@@ -0,0 +1,70 @@
+package paypal_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal"
+)
+
+func TestClientOptions(t *testing.T) {
+	var userAgent, version string
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		userAgent = r.UserAgent()
+		version = r.PostForm.Get("VERSION")
+		w.Write([]byte("ACK=Success&TOKEN=EC-123"))
+	},
+		paypal.WithUserAgent("shop/1.0"),
+		paypal.WithAPIVersion("124"),
+		paypal.WithCheckoutUrl("https://checkout.example.com/webscr"),
+	)
+
+	response, err := client.CreateBillingAgreementContext(t.Context(), "EC-123")
+	if err != nil {
+		t.Fatalf("CreateBillingAgreementContext returned error: %v", err)
+	}
+	if userAgent != "shop/1.0" || version != "124" {
+		t.Errorf("Expected custom user agent and version, got %q and %q", userAgent, version)
+	}
+	if !strings.HasPrefix(response.GetCheckoutUrl(), "https://checkout.example.com/webscr?") {
+		t.Errorf("Expected the custom checkout URL, got %s", response.GetCheckoutUrl())
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+		}
+	}, paypal.WithTimeout(10*time.Millisecond))
+
+	_, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123")
+	if !errors.Is(err, paypal.ErrDeadlineExceeded) {
+		t.Errorf("Expected ErrDeadlineExceeded, got %v", err)
+	}
+}
+
+func TestOnBehalfOf(t *testing.T) {
+	var subjects []string
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		subjects = append(subjects, r.PostForm.Get("SUBJECT"))
+		w.Write([]byte("ACK=Success"))
+	})
+
+	merchant := client.OnBehalfOf("merchant@example.com")
+	for _, c := range []*paypal.PayPalClient{merchant, client} {
+		if _, err := c.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+			t.Fatalf("GetExpressCheckoutDetailsContext returned error: %v", err)
+		}
+	}
+
+	if len(subjects) != 2 || subjects[0] != "merchant@example.com" || subjects[1] != "" {
+		t.Errorf("Expected SUBJECT only on the merchant client's call, got %q", subjects)
+	}
+}
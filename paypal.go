@@ -1,12 +1,19 @@
 package paypal
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/crowdmob/paypal/nvp"
 )
 
 const (
@@ -15,444 +22,947 @@ const (
 	CHECKOUT_SANDBOX_URL    = "https://www.sandbox.paypal.com/cgi-bin/webscr"
 	CHECKOUT_PRODUCTION_URL = "https://www.paypal.com/cgi-bin/webscr"
 	NVP_VERSION             = "86"
+	MAX_PAYMENT_REQUESTS    = 10
+)
+
+// Possible values of ACK
+const (
+	ACK_SUCCESS              = "Success"
+	ACK_SUCCESS_WITH_WARNING = "SuccessWithWarning"
+	ACK_PARTIAL_SUCCESS      = "PartialSuccess" // some of the parallel payments failed
+	ACK_FAILURE              = "Failure"
+	ACK_FAILURE_WITH_WARNING = "FailureWithWarning"
 )
 
 type PayPalClient struct {
-	username    string
-	password    string
-	signature   string
+	credentials CredentialProvider
 	usesSandbox bool
 	client      *http.Client
+	retryPolicy *RetryPolicy
+
+	endpoint    string
+	checkoutUrl string
+	version     string
+	userAgent   string
+	timeout     time.Duration
+	certificate *tls.Certificate // API certificate credentials, used instead of signature
+	subject     string           // merchant the calls are made on behalf of
+
+	idempotencyStore IdempotencyStore
+	middleware       []Middleware
+	logger           *slog.Logger
+	metrics          Metrics
+	tracer           Tracer
+
+	rateLimiter        *rateLimiter
+	methodRateLimiters map[string]*rateLimiter
+	breaker            *CircuitBreaker
 }
 
 type PayPalDigitalGood struct {
 	Name     string
-	Amount   float64
+	Amount   Money
 	Quantity int16
 }
 
 type PaymentInfo struct {
-	TransactionId             string
-	ParentTransactionId       string
-	ReceiptId                 string
-	TransactionType           string // can be "cart" or "express-checkout"
-	PaymentType               string // can be "none", "echeck", or "instant"
-	OrderTime                 string // UTC/GMT
-	Amount                    float64
-	CurrencyCode              string
-	FeeAmount                 float64
-	SettleAmount              float64
-	TaxAmount                 float64
-	ExchangeRate              float64
-	PaymentStatus             string   // can be "None", "Cancel-Reversal", "Completed", "Denied", "Expired", "Failed", "In-Progress", "Partially-Refunded", "Pending", "Refunded", "Reversed", "Processed", or "Voided"
-	PendingReason             string   // can be "none", "address", "authorization", "echeck", "intl", "multi-currency", "order", "payment-review", "regulatory-review", "unilateral", "verify", or "other"; only returned if PaymentStatus == "Pending"
-	ReasonCode                string   // can be "none", "chargeback", "guarantee", "buyer-complaint", "refund", or "other"
-	ProtectionEligibility     string   // can be "Eligible", "PartiallyEligible", or "Ineligible"
-	ProtectionEligibilityType []string // can be "ItemNotReceivedEligible", "UnauthorizedPaymentEligible", or "Ineligible"
-	StoreId                   string
-	TerminalId                string
-	InstrumentCategory        int    // Possible values are 1, which represents PayPal credit
-	InstrumentId              string // Reserved for future use
+	TransactionId             string                `nvp:"TRANSACTIONID"`
+	ParentTransactionId       string                `nvp:"PARENTTRANSACTIONID"`
+	ReceiptId                 string                `nvp:"RECEIPTID"`
+	TransactionType           TransactionType       `nvp:"TRANSACTIONTYPE"`
+	PaymentType               string                `nvp:"PAYMENTTYPE"` // can be "none", "echeck", or "instant"
+	OrderTime                 time.Time             `nvp:"-"`
+	RawOrderTime              string                `nvp:"ORDERTIME"` // as returned by PayPal
+	OrderTimeErr              error                 `nvp:"-"`         // set if RawOrderTime isn't an RFC 3339 time
+	Amount                    Money                 `nvp:"AMT,currency=CURRENCYCODE"`
+	CurrencyCode              string                `nvp:"CURRENCYCODE"`
+	FeeAmount                 Money                 `nvp:"FEEAMT,currency=CURRENCYCODE"`
+	SettleAmount              Money                 `nvp:"SETTLEAMT"` // in the receiving account's currency, which NVP does not report
+	TaxAmount                 Money                 `nvp:"TAXAMT,currency=CURRENCYCODE"`
+	ExchangeRate              float64               `nvp:"EXCHANGERATE"`
+	PaymentStatus             PaymentStatus         `nvp:"PAYMENTSTATUS"`
+	PendingReason             PendingReason         `nvp:"PENDINGREASON"` // only set if PaymentStatus is PAYMENT_STATUS_PENDING
+	ReasonCode                ReasonCode            `nvp:"REASONCODE"`
+	ProtectionEligibility     ProtectionEligibility `nvp:"PROTECTIONELIGIBILITY"`
+	ProtectionEligibilityType []string              `nvp:"PROTECTIONELIGIBILITYTYPE,comma"` // can be "ItemNotReceivedEligible", "UnauthorizedPaymentEligible", or "Ineligible"
+	StoreId                   string                `nvp:"STOREID"`
+	TerminalId                string                `nvp:"TERMINALID"`
+	InstrumentCategory        int                   `nvp:"INSTRUMENTCATEGORY"` // Possible values are 1, which represents PayPal credit
+	InstrumentId              string                `nvp:"INSTRUMENTID"`       // Reserved for future use
+	PaymentRequestId          string                `nvp:"PAYMENTREQUESTID"`   // of the parallel payment this is
 }
 
 type AddressInfo struct {
-	Name              string
-	Street            string
-	Street2           string
-	City              string
-	State             string
-	Zip               string
-	CountryCode       string
-	Country           string
-	PhoneNumber       string
-	Status            string // none, Confirmed, or Unconfirmed
-	NormatilzedStatus string // For Brazil only: none, Normalized, Unnormalized, or UserPrefered
+	Name              string `nvp:"SHIPTONAME,omitempty"`
+	Street            string `nvp:"SHIPTOSTREET,omitempty"`
+	Street2           string `nvp:"SHIPTOSTREET2,omitempty"`
+	City              string `nvp:"SHIPTOCITY,omitempty"`
+	State             string `nvp:"SHIPTOSTATE,omitempty"`
+	Zip               string `nvp:"SHIPTOZIP,omitempty"`
+	CountryCode       string `nvp:"SHIPTOCOUNTRYCODE,omitempty"`
+	Country           string `nvp:"SHIPTOCOUNTRYNAME,omitempty"`
+	PhoneNumber       string `nvp:"SHIPTOPHONENUM,omitempty"`
+	Status            string `nvp:"ADDRESSSTATUS,omitempty"`              // none, Confirmed, or Unconfirmed
+	NormatilzedStatus string `nvp:"ADDRESSNORMALIZATIONSTATUS,omitempty"` // For Brazil only: none, Normalized, Unnormalized, or UserPrefered
+}
+
+// PaymentRequest is a PAYMENTREQUEST_n_ section of an Express Checkout: one
+// payment, with its amounts, items and shipping address.
+type PaymentRequest struct {
+	Amount                 Money         `nvp:"AMT,currency=CURRENCYCODE"`
+	CurrencyCode           string        `nvp:"CURRENCYCODE,omitempty"`
+	ItemAmount             Money         `nvp:"ITEMAMT,currency=CURRENCYCODE,omitempty"` // sum of the items
+	ShippingAmount         Money         `nvp:"SHIPPINGAMT,currency=CURRENCYCODE,omitempty"`
+	InsuranceAmount        Money         `nvp:"INSURANCEAMT,currency=CURRENCYCODE,omitempty"`
+	ShippingDiscount       Money         `nvp:"SHIPDISCAMT,currency=CURRENCYCODE,omitempty"` // negative, and always sent as such
+	HandlingAmount         Money         `nvp:"HANDLINGAMT,currency=CURRENCYCODE,omitempty"`
+	TaxAmount              Money         `nvp:"TAXAMT,currency=CURRENCYCODE,omitempty"`
+	InsuranceOptionOffered TrueFalse     `nvp:"INSURANCEOPTIONOFFERED,omitempty"`
+	Description            string        `nvp:"DESC,omitempty"`
+	Custom                 string        `nvp:"CUSTOM,omitempty"`
+	InvoiceId              string        `nvp:"INVNUM,omitempty"`
+	NotifyUrl              string        `nvp:"NOTIFYURL,omitempty"`
+	NoteText               string        `nvp:"NOTETEXT,omitempty"` // the buyer's note to the merchant
+	TransactionId          string        `nvp:"TRANSACTIONID,omitempty"`
+	AllowedPaymentMethod   string        `nvp:"ALLOWEDPAYMENTMETHOD,omitempty"`
+	PaymentRequestId       string        `nvp:"PAYMENTREQUESTID,omitempty"`
+	PaymentAction          string        `nvp:"PAYMENTACTION,omitempty"`         // "Sale", "Authorization" or "Order"
+	SellerPayPalAccountId  string        `nvp:"SELLERPAYPALACCOUNTID,omitempty"` // email address or payer ID of the seller paid
+	ShippingAddress        AddressInfo   `nvp:",inline"`
+	Items                  []PaymentItem `nvp:",list"` // L_PAYMENTREQUEST_n_NAMEm and so on
+}
+
+// TrueFalse is a bool sent as "true" or "false", for the fields PayPal
+// doesn't accept 1 and 0 in.
+type TrueFalse bool
+
+func (b TrueFalse) MarshalNVP() (string, error) {
+	return strconv.FormatBool(bool(b)), nil
+}
+
+func (b *TrueFalse) UnmarshalNVP(value string) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*b = TrueFalse(parsed)
+	return nil
+}
+
+// PaymentItem is an item of a PaymentRequest.
+type PaymentItem struct {
+	Name         string `nvp:"NAME"`
+	Description  string `nvp:"DESC,omitempty"`
+	Amount       Money  `nvp:"AMT,currency=CURRENCYCODE"`
+	Number       string `nvp:"NUMBER,omitempty"`
+	Quantity     int    `nvp:"QTY"`
+	TaxAmount    Money  `nvp:"TAXAMT,currency=CURRENCYCODE,omitempty"`
+	ItemCategory string `nvp:"ITEMCATEGORY,omitempty"` // "Digital" or "Physical"
+	ItemUrl      string `nvp:"ITEMURL,omitempty"`
+}
+
+// ExpressCheckout is a SetExpressCheckout request. Each of PaymentRequests
+// pays one seller, up to MAX_PAYMENT_REQUESTS; when there is more than one,
+// each needs a PaymentRequestId and a SellerPayPalAccountId.
+type ExpressCheckout struct {
+	ReturnUrl          string             `nvp:"RETURNURL"`
+	CancelUrl          string             `nvp:"CANCELURL"`
+	MaxAmount          Money              `nvp:"MAXAMT,omitempty"`
+	NoShipping         int                `nvp:"NOSHIPPING,omitempty"` // 1 to not ask for a shipping address, 2 to use the buyer's
+	ReqConfirmShipping bool               `nvp:"REQCONFIRMSHIPPING"`
+	SolutionType       string             `nvp:"SOLUTIONTYPE,omitempty"` // "Sole" to allow checkout without a PayPal account, or "Mark"
+	LandingPage        string             `nvp:"LANDINGPAGE,omitempty"`  // "Billing" or "Login"
+	BrandName          string             `nvp:"BRANDNAME,omitempty"`
+	LocaleCode         string             `nvp:"LOCALECODE,omitempty"`
+	Email              string             `nvp:"EMAIL,omitempty"`
+	BillingAgreements  []BillingAgreement `nvp:",list"`
+	PaymentRequests    []PaymentRequest   `nvp:"-"`
+}
+
+type BillingAgreement struct {
+	Type        string `nvp:"BILLINGTYPE"` // "MerchantInitiatedBilling" or "MerchantInitiatedBillingSingleAgreement"
+	Description string `nvp:"BILLINGAGREEMENTDESCRIPTION,omitempty"`
+	PaymentType string `nvp:"PAYMENTTYPE,omitempty"` // "Any" or "InstantOnly"
+	Custom      string `nvp:"BILLINGAGREEMENTCUSTOM,omitempty"`
 }
 
 type PayPalResponse struct {
 	Ack           string
 	CorrelationId string
-	Timestamp     string
+	Timestamp     time.Time
+	RawTimestamp  string // TIMESTAMP as returned by PayPal
+	TimestampErr  error  // set if RawTimestamp isn't an RFC 3339 time
+	DecodeErr     error  // a *nvp.DecodeError, set if other values were malformed
 	Version       string
 	Build         string
 	Values        url.Values
-	usedSandbox   bool
+	Warnings      []ErrorEntry // errors reported alongside a successful ACK
+	checkoutUrl   string
 }
 
 type PayPalSetExpressCheckoutResponse struct {
-	PayPalResponse
+	PayPalResponse `nvp:"-"`
 
-	Token string
+	Token string `nvp:"TOKEN"`
 }
 
 type PayPalBillingAgreementResponse struct {
-	PayPalResponse
+	PayPalResponse `nvp:"-"`
 
-	BillingAgreementId string
+	BillingAgreementId string `nvp:"BILLINGAGREEMENTID"`
 }
 
 type PayPalExpressCheckoutDetails struct {
-	PayPalResponse
-
-	Token                    string
-	PhoneNumber              string
-	BillingAgreementAccepted bool
-	CheckoutStatus           string
-	PayerID                  string
-	Email                    string
-	PayerStatusVerified      bool
-	FirstName                string
-	LastName                 string
-	CountryCode              string
-	ShippingAddresses        []AddressInfo
-	PaymentsInfo             []PaymentInfo
+	PayPalResponse `nvp:"-"`
+
+	Token                    string         `nvp:"TOKEN"`
+	PhoneNumber              string         `nvp:"PHONENUM"`
+	BillingAgreementAccepted bool           `nvp:"BILLINGAGREEMENTACCEPTEDSTATUS"`
+	CheckoutStatus           CheckoutStatus `nvp:"CHECKOUTSTATUS"`
+	PayerID                  string         `nvp:"PAYERID"`
+	Email                    string         `nvp:"EMAIL"`
+	PayerStatusVerified      bool           `nvp:"-"`
+	FirstName                string         `nvp:"FIRSTNAME"`
+	LastName                 string         `nvp:"LASTNAME"`
+	CountryCode              string         `nvp:"COUNTRYCODE"`
+	BuyerMarketingEmail      string         `nvp:"BUYERMARKETINGEMAIL"`
+	Note                     string         `nvp:"NOTE"`
+
+	// Gift Options
+	GiftMessage        string `nvp:"GIFTMESSAGE"`
+	GiftReceiptEnabled bool   `nvp:"GIFTRECEIPTENABLE"`
+	GiftWrapName       string `nvp:"GIFTWRAPNAME"`
+	GiftWrapAmount     Money  `nvp:"GIFTWRAPAMOUNT,currency=PAYMENTREQUEST_0_CURRENCYCODE"`
+
+	PaymentRequests   []PaymentRequest `nvp:"PAYMENTREQUEST,section"`
+	ShippingAddresses []AddressInfo    `nvp:"-"` // one per entry in PaymentRequests
+	PaymentsInfo      []PaymentInfo    `nvp:"-"` // one per entry in PaymentRequests
 }
 
 type PayPalExpressPaymentResponse struct {
-	PayPalResponse
+	PayPalResponse `nvp:"-"`
 
-	Token                        string
-	BillingAgreementId           string
-	RedirectRequired             bool
-	Note                         string
-	MsgSubId                     string
-	SuccessPageRedirectRequested bool
-	PaymentsInfo                 []PaymentInfo
+	Token                        string        `nvp:"TOKEN"`
+	BillingAgreementId           string        `nvp:"BILLINGAGREEMENTID"`
+	RedirectRequired             bool          `nvp:"REDIRECTREQUIRED"`
+	Note                         string        `nvp:"NOTE"`
+	MsgSubId                     string        `nvp:"MSGSUBID"`
+	SuccessPageRedirectRequested bool          `nvp:"SUCCESSPAGEREDIRECTREQUESTED"`
+	PaymentsInfo                 []PaymentInfo `nvp:"-"`
 
 	// User Options Info
+	UserSelectedOptions UserSelectedOptions `nvp:",inline"`
 
 	// Error Info
+	PaymentErrors []ErrorEntry `nvp:"-"` // only payment requests that failed
 
 	// Seller Info
+	Sellers []SellerInfo `nvp:"-"` // one per entry in PaymentsInfo
 
 	// Risk Info
+	FraudFilters []FraudFilter `nvp:"-"`
 }
 
-type PayPalReferenceTransactionResponse struct {
-	PayPalResponse
-	PaymentInfo
+type UserSelectedOptions struct {
+	ShippingCalculationMode string `nvp:"SHIPPINGCALCULATIONMODE"` // "Callback" or "Flat-Rate"
+	InsuranceOptionSelected bool   `nvp:"INSURANCEOPTIONSELECTED"`
+	ShippingOptionIsDefault bool   `nvp:"SHIPPINGOPTIONISDEFAULT"`
+	ShippingOptionAmount    Money  `nvp:"SHIPPINGOPTIONAMOUNT,currency=PAYMENTINFO_0_CURRENCYCODE"`
+	ShippingOptionName      string `nvp:"SHIPPINGOPTIONNAME"`
+}
+
+type SellerInfo struct {
+	SellerPayPalAccountId   string `nvp:"SELLERPAYPALACCOUNTID"`
+	SellerId                string `nvp:"SELLERID"`
+	SellerUserName          string `nvp:"SELLERUSERNAME"`
+	SellerRegistrationDate  string `nvp:"SELLERREGISTRATIONDATE"`
+	SecureMerchantAccountId string `nvp:"SECUREMERCHANTACCOUNTID"`
+}
+
+// FraudFilter is a Fraud Management Filter that was triggered by a payment
+type FraudFilter struct {
+	PaymentRequest int    // index n of PAYMENTINFO_n
+	Action         string // "Pending", "Report" or "Deny"
+	Id             string
+	Name           string
+}
 
-	AvsCode            string
-	Cvv2Match          string
-	BillingAgreementId string
-	PaymentAdviceCode  string
-	MsgSubId           string
+type PayPalReferenceTransactionResponse struct {
+	PayPalResponse `nvp:"-"`
+	PaymentInfo    `nvp:",inline"`
+
+	AvsCode            string `nvp:"AVSCODE"`
+	Cvv2Match          string `nvp:"CVV2MATCH"`
+	BillingAgreementId string `nvp:"BILLINGAGREEMENTID"`
+	PaymentAdviceCode  string `nvp:"PAYMENTADVICECODE"`
+	MsgSubId           string `nvp:"MSGSUBID"`
 }
 
 type PayPalRefundTransactionResponse struct {
-	PayPalResponse
+	PayPalResponse `nvp:"-"`
+
+	RefundTransactionId string        `nvp:"REFUNDTRANSACTIONID"`
+	RefundFeeAmount     Money         `nvp:"FEEREFUNDAMT,currency=CURRENCYCODE"`   // 2.9% of the refund + $.30
+	GrossRefundAmount   Money         `nvp:"GROSSREFUNDAMT,currency=CURRENCYCODE"` // Amount refunded from this request
+	NetRefundAmount     Money         `nvp:"NETREFUNDAMT,currency=CURRENCYCODE"`   // GrossRefundAmt - RefundFeeAmt
+	TotalRefundAmount   Money         `nvp:"TOTALREFUNDAMT,currency=CURRENCYCODE"` // Total amount refunded for this transaction
+	CurrencyCode        string        `nvp:"CURRENCYCODE"`
+	RefundStatus        string        `nvp:"REFUNDSTATUS"`  // instant, delayed, or none if transaction fails
+	PendingReason       PendingReason `nvp:"PENDINGREASON"` // none, echeck, or regulatory-review
+	MsgSubId            string        `nvp:"MSGSUBID"`
+}
 
-	RefundTransactionId string
-	RefundFeeAmount     float64 // 2.9% of the refund + $.30
-	GrossRefundAmount   float64 // Amount refunded from this request
-	NetRefundAmount     float64 // GrossRefundAmt - RefundFeeAmt
-	TotalRefundAmount   float64 // Total amount refunded for this transaction
-	CurrencyCode        string
-	RefundStatus        string // instant, delayed, or none if transaction fails
-	PendingReason       string // none, echeck, or regulatoryreview
-	MsgSubId            string
+// IsSuccess reports whether PayPal processed the request, possibly with
+// warnings.
+func (r *PayPalResponse) IsSuccess() bool {
+	return strings.EqualFold(r.Ack, ACK_SUCCESS) || r.IsWarning()
 }
 
-type PayPalError struct {
-	Ack          string
-	ErrorCode    string
-	ShortMessage string
-	LongMessage  string
-	SeverityCode string
+// IsWarning reports whether PayPal processed the request but returned
+// warnings, e.g. 11607 when a MSGSUBID was already used.
+func (r *PayPalResponse) IsWarning() bool {
+	return strings.EqualFold(r.Ack, ACK_SUCCESS_WITH_WARNING)
 }
 
-func (e *PayPalError) Error() string {
-	var message string
-	if len(e.ErrorCode) != 0 && len(e.ShortMessage) != 0 {
-		message = "PayPal Error " + e.ErrorCode + ": " + e.ShortMessage
-	} else if len(e.Ack) != 0 {
-		message = e.Ack
-	} else {
-		message = "PayPal is undergoing maintenance.\nPlease try again later."
-	}
+// IsPartialSuccess reports whether only some of the parallel payments of a
+// DoExpressCheckoutPayment went through. It's not a success: the call also
+// returns a *PayPalError listing the payment requests that failed.
+func (r *PayPalResponse) IsPartialSuccess() bool {
+	return strings.EqualFold(r.Ack, ACK_PARTIAL_SUCCESS)
+}
 
-	return message
+// Payment returns the payment made for the payment request with the given
+// PaymentRequestId.
+func (r *PayPalExpressPaymentResponse) Payment(paymentRequestId string) (*PaymentInfo, bool) {
+	for i := range r.PaymentsInfo {
+		if r.PaymentsInfo[i].PaymentRequestId == paymentRequestId {
+			return &r.PaymentsInfo[i], true
+		}
+	}
+	return nil, false
 }
 
 func (r *PayPalResponse) GetCheckoutUrl() string {
 	query := url.Values{}
 	query.Set("cmd", "_express-checkout")
 	query.Add("token", r.Values["TOKEN"][0])
-	checkoutUrl := CHECKOUT_PRODUCTION_URL
-	if r.usedSandbox {
-		checkoutUrl = CHECKOUT_SANDBOX_URL
-	}
-	return fmt.Sprintf("%s?%s", checkoutUrl, query.Encode())
+	return fmt.Sprintf("%s?%s", r.checkoutUrl, query.Encode())
 }
 
+// Deprecated: use SumDigitalGoods, which does not lose precision.
 func SumPayPalDigitalGoodAmounts(goods *[]PayPalDigitalGood) (sum float64) {
 	for _, dg := range *goods {
-		sum += dg.Amount * float64(dg.Quantity)
+		sum += dg.Amount.Float64() * float64(dg.Quantity)
 	}
 	return
 }
 
 func NewDefaultClient(username, password, signature string, usesSandbox bool) *PayPalClient {
-	return &PayPalClient{username, password, signature, usesSandbox, new(http.Client)}
+	return NewClientWithOptions(username, password, signature, WithSandbox(usesSandbox))
 }
 
 func NewClient(username, password, signature string, usesSandbox bool, client *http.Client) *PayPalClient {
-	return &PayPalClient{username, password, signature, usesSandbox, client}
+	return NewClientWithOptions(username, password, signature, WithSandbox(usesSandbox), WithHTTPClient(client))
+}
+
+// OnBehalfOf returns a client making calls for the merchant identified by
+// subject (their PayPal email address or payer ID), who must have granted
+// this account API permissions. The new client shares pClient's credentials,
+// http.Client and settings.
+func (pClient *PayPalClient) OnBehalfOf(subject string) *PayPalClient {
+	merchantClient := *pClient
+	merchantClient.subject = subject
+	return &merchantClient
 }
 
 func (pClient *PayPalClient) PerformRequest(values url.Values) (*PayPalResponse, error) {
-	values.Add("USER", pClient.username)
-	values.Add("PWD", pClient.password)
-	values.Add("SIGNATURE", pClient.signature)
-	values.Add("VERSION", NVP_VERSION)
+	return pClient.PerformRequestContext(context.Background(), values)
+}
+
+func (pClient *PayPalClient) PerformRequestContext(ctx context.Context, values url.Values) (*PayPalResponse, error) {
+	ctx, span := pClient.startSpan(ctx, values)
+	start := time.Now()
+	response, err := pClient.performRequest(ctx, values)
+	duration := time.Since(start)
+	endSpan(span, response, err)
+	pClient.logRequest(ctx, values, response, err, duration)
+	pClient.observeRequest(values, response, err, duration)
+	return response, err
+}
 
-	endpoint := NVP_PRODUCTION_URL
-	if pClient.usesSandbox {
-		endpoint = NVP_SANDBOX_URL
+func (pClient *PayPalClient) performRequest(ctx context.Context, values url.Values) (*PayPalResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(ctx, err)
 	}
 
-	formResponse, err := pClient.client.PostForm(endpoint, values)
+	// the caller's values are left alone, so they never contain credentials
+	values = copyValues(values)
+
+	if err := pClient.applyIdempotencyKey(ctx, values); err != nil {
+		return nil, err
+	}
+
+	credentials, err := pClient.credentials.Credentials(ctx)
 	if err != nil {
 		return nil, err
 	}
+
+	values.Add("USER", credentials.Username)
+	values.Add("PWD", credentials.Password)
+	if pClient.certificate == nil {
+		values.Add("SIGNATURE", credentials.Signature)
+	}
+	values.Add("VERSION", pClient.version)
+	if len(pClient.subject) != 0 {
+		values.Set("SUBJECT", pClient.subject)
+	}
+
+	if pClient.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, pClient.timeout)
+		defer cancel()
+	}
+
+	transport := pClient.roundTripper()
+	for attempt := 1; ; attempt++ {
+		if pClient.breaker != nil {
+			if err := pClient.breaker.allow(); err != nil {
+				return nil, err
+			}
+		}
+		release, err := pClient.acquireRequest(ctx, values.Get("METHOD"))
+		if err != nil {
+			if pClient.breaker != nil {
+				pClient.breaker.release()
+			}
+			return nil, err
+		}
+
+		// every attempt gets its own copy, so middleware changing the values
+		// doesn't affect the retries
+		response, err := transport.RoundTrip(ctx, copyValues(values))
+		release()
+		if pClient.breaker != nil {
+			pClient.breaker.record(err)
+		}
+		if err == nil || !pClient.retryPolicy.shouldRetry(attempt, values, err) {
+			return response, err
+		}
+
+		if err := pClient.retryPolicy.wait(ctx, attempt); err != nil {
+			return nil, contextError(ctx, err)
+		}
+	}
+}
+
+func (pClient *PayPalClient) performAttempt(ctx context.Context, values url.Values) (*PayPalResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", pClient.endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(pClient.userAgent) != 0 {
+		req.Header.Set("User-Agent", pClient.userAgent)
+	}
+
+	formResponse, err := pClient.client.Do(req)
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
 	defer formResponse.Body.Close()
 
 	body, err := ioutil.ReadAll(formResponse.Body)
 	if err != nil {
-		return nil, err
+		return nil, contextError(ctx, err)
+	}
+
+	if formResponse.StatusCode < 200 || formResponse.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: formResponse.StatusCode, Status: formResponse.Status}
 	}
 
+	response := &PayPalResponse{checkoutUrl: pClient.checkoutUrl}
 	responseValues, err := url.ParseQuery(string(body))
-	response := &PayPalResponse{usedSandbox: pClient.usesSandbox}
-	if err == nil {
-		response.Ack = responseValues.Get("ACK")
-		response.CorrelationId = responseValues.Get("CORRELATIONID")
-		response.Timestamp = responseValues.Get("TIMESTAMP")
-		response.Version = responseValues.Get("VERSION")
-		response.Build = responseValues.Get("2975009")
-		response.Values = responseValues
-
-		errorCode := responseValues.Get("L_ERRORCODE0")
-		if len(errorCode) != 0 || strings.ToLower(response.Ack) == "failure" || strings.ToLower(response.Ack) == "failurewithwarning" {
-			pError := new(PayPalError)
-			pError.Ack = response.Ack
-			pError.ErrorCode = errorCode
-			pError.ShortMessage = responseValues.Get("L_SHORTMESSAGE0")
-			pError.LongMessage = responseValues.Get("L_LONGMESSAGE0")
-			pError.SeverityCode = responseValues.Get("L_SEVERITYCODE0")
-
-			err = pError
-		}
+	if err != nil || (len(responseValues.Get("ACK")) == 0 && len(responseValues.Get("L_ERRORCODE0")) == 0) {
+		// Not an NVP response, which is what PayPal serves during maintenance
+		return response, newPayPalError("", nil)
+	}
+
+	response.Ack = responseValues.Get("ACK")
+	response.CorrelationId = responseValues.Get("CORRELATIONID")
+	response.RawTimestamp = responseValues.Get("TIMESTAMP")
+	response.Timestamp, response.TimestampErr = ParseTimestamp("TIMESTAMP", response.RawTimestamp)
+	response.Version = responseValues.Get("VERSION")
+	response.Build = responseValues.Get("2975009")
+	response.Values = responseValues
+
+	entries := parseErrors(responseValues)
+	errorCode := responseValues.Get("L_ERRORCODE0")
+	if response.IsSuccess() {
+		response.Warnings = entries
+	} else if response.IsPartialSuccess() || len(errorCode) != 0 || strings.EqualFold(response.Ack, ACK_FAILURE) || strings.EqualFold(response.Ack, ACK_FAILURE_WITH_WARNING) {
+		err = newPayPalError(response.Ack, entries)
 	}
 
 	return response, err
 }
 
+// unmarshal decodes the typed fields of a response into each of targets.
+// Malformed values are reported together as a *nvp.DecodeError in
+// DecodeErr rather than failing the call, which PayPal has processed.
+func (r *PayPalResponse) unmarshal(targets ...interface{}) error {
+	var errs []*nvp.FieldError
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		err := nvp.UnmarshalStrict(r.Values, target)
+		var decodeError *nvp.DecodeError
+		if !errors.As(err, &decodeError) {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		// targets may decode the same keys, e.g. a section twice
+		for _, fieldError := range decodeError.Errors {
+			if !seen[fieldError.Key] {
+				seen[fieldError.Key] = true
+				errs = append(errs, fieldError)
+			}
+		}
+	}
+	if len(errs) != 0 {
+		r.DecodeErr = &nvp.DecodeError{Errors: errs}
+	}
+	return nil
+}
+
+// Deprecated: use SetExpressCheckoutBillingAgreementContext, which takes Money amounts.
 func (pClient *PayPalClient) SetExpressCheckoutBillingAgreement(maxAmt, paymentAmount float64, currencyCode, billingAgreementDescription, returnUrl, cancelUrl string) (*PayPalSetExpressCheckoutResponse, error) {
-	values := url.Values{}
-	values.Set("METHOD", "SetExpressCheckout")
-	values.Add("MAXAMT", fmt.Sprintf("%.2f", maxAmt))
-	values.Add("PAYMENTREQUEST_0_AMT", fmt.Sprintf("%.2f", paymentAmount))
-	values.Add("PAYMENTREQUEST_0_PAYMENTACTION", "AUTHORIZATION")
-	values.Add("PAYMENTREQUEST_0_CURRENCYCODE", currencyCode)
-	values.Add("RETURNURL", returnUrl)
-	values.Add("CANCELURL", cancelUrl)
-	values.Add("NOSHIPPING", "1")
-	values.Add("REQCONFIRMSHIPPING", "0")
-	values.Add("L_BILLINGTYPE0", "MerchantInitiatedBilling")
-	values.Add("L_BILLINGAGREEMENTDESCRIPTION0", billingAgreementDescription)
-
-	resp, err := pClient.PerformRequest(values)
+	maxAmount, err := exactMoneyFromFloat("MAXAMT", maxAmt, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := exactMoneyFromFloat("PAYMENTREQUEST_0_AMT", paymentAmount, currencyCode)
 	if err != nil {
 		return nil, err
 	}
+	return pClient.SetExpressCheckoutBillingAgreementContext(context.Background(), maxAmount, amount, billingAgreementDescription, returnUrl, cancelUrl)
+}
 
-	return &PayPalSetExpressCheckoutResponse{
-		PayPalResponse: *resp,
-		Token:          resp.Values.Get("TOKEN"),
-	}, nil
+func (pClient *PayPalClient) SetExpressCheckoutBillingAgreementContext(ctx context.Context, maxAmt, paymentAmount Money, billingAgreementDescription, returnUrl, cancelUrl string) (*PayPalSetExpressCheckoutResponse, error) {
+	return pClient.SetExpressCheckoutContext(ctx, &ExpressCheckout{
+		ReturnUrl:  returnUrl,
+		CancelUrl:  cancelUrl,
+		MaxAmount:  maxAmt,
+		NoShipping: 1,
+		BillingAgreements: []BillingAgreement{
+			{Type: "MerchantInitiatedBilling", Description: billingAgreementDescription},
+		},
+		PaymentRequests: []PaymentRequest{
+			{Amount: paymentAmount, PaymentAction: "AUTHORIZATION"},
+		},
+	})
 }
 
+// Deprecated: use SetExpressCheckoutDigitalGoodsContext, which takes Money amounts.
 func (pClient *PayPalClient) SetExpressCheckoutDigitalGoods(paymentAmount float64, currencyCode, returnUrl, cancelUrl string, goods []PayPalDigitalGood) (*PayPalSetExpressCheckoutResponse, error) {
-	values := url.Values{}
-	values.Set("METHOD", "SetExpressCheckout")
-	values.Add("PAYMENTREQUEST_0_AMT", fmt.Sprintf("%.2f", paymentAmount))
-	values.Add("PAYMENTREQUEST_0_PAYMENTACTION", "Sale")
-	values.Add("PAYMENTREQUEST_0_CURRENCYCODE", currencyCode)
-	values.Add("RETURNURL", returnUrl)
-	values.Add("CANCELURL", cancelUrl)
-	values.Add("REQCONFIRMSHIPPING", "0")
-	values.Add("NOSHIPPING", "1")
-	values.Add("SOLUTIONTYPE", "Sole")
+	amount, err := exactMoneyFromFloat("PAYMENTREQUEST_0_AMT", paymentAmount, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	return pClient.SetExpressCheckoutDigitalGoodsContext(context.Background(), amount, returnUrl, cancelUrl, goods)
+}
+
+func (pClient *PayPalClient) SetExpressCheckoutDigitalGoodsContext(ctx context.Context, paymentAmount Money, returnUrl, cancelUrl string, goods []PayPalDigitalGood) (*PayPalSetExpressCheckoutResponse, error) {
+	request := PaymentRequest{Amount: paymentAmount, PaymentAction: "Sale"}
+	for _, good := range goods {
+		request.Items = append(request.Items, PaymentItem{
+			Name:         good.Name,
+			Amount:       good.Amount,
+			Quantity:     int(good.Quantity),
+			ItemCategory: "Digital",
+		})
+	}
+
+	return pClient.SetExpressCheckoutContext(ctx, &ExpressCheckout{
+		ReturnUrl:       returnUrl,
+		CancelUrl:       cancelUrl,
+		NoShipping:      1,
+		SolutionType:    "Sole",
+		PaymentRequests: []PaymentRequest{request},
+	})
+}
 
-	for i := 0; i < len(goods); i++ {
-		good := goods[i]
+func (pClient *PayPalClient) SetExpressCheckout(checkout *ExpressCheckout) (*PayPalSetExpressCheckoutResponse, error) {
+	return pClient.SetExpressCheckoutContext(context.Background(), checkout)
+}
+
+// SetExpressCheckoutContext starts a checkout paying every payment request
+// of checkout, possibly to different sellers.
+func (pClient *PayPalClient) SetExpressCheckoutContext(ctx context.Context, checkout *ExpressCheckout) (*PayPalSetExpressCheckoutResponse, error) {
+	if err := ValidateAmount("MAXAMT", checkout.MaxAmount); err != nil {
+		return nil, err
+	}
+	if !checkout.MaxAmount.IsZero() && len(checkout.PaymentRequests) != 0 {
+		if _, err := sameCurrency(checkout.MaxAmount, checkout.PaymentRequests[0].Amount); err != nil {
+			return nil, err
+		}
+	}
 
-		values.Add(fmt.Sprintf("%s%d", "L_PAYMENTREQUEST_0_NAME", i), good.Name)
-		values.Add(fmt.Sprintf("%s%d", "L_PAYMENTREQUEST_0_AMT", i), fmt.Sprintf("%.2f", good.Amount))
-		values.Add(fmt.Sprintf("%s%d", "L_PAYMENTREQUEST_0_QTY", i), fmt.Sprintf("%d", good.Quantity))
-		values.Add(fmt.Sprintf("%s%d", "L_PAYMENTREQUEST_0_ITEMCATEGORY", i), "Digital")
+	values := url.Values{}
+	values.Set("METHOD", "SetExpressCheckout")
+	if err := marshalInto(values, checkout); err != nil {
+		return nil, err
+	}
+	if err := addPaymentRequests(values, checkout.PaymentRequests); err != nil {
+		return nil, err
 	}
 
-	resp, err := pClient.PerformRequest(values)
+	resp, err := pClient.PerformRequestContext(ctx, values)
 	if err != nil {
 		return nil, err
 	}
 
-	return &PayPalSetExpressCheckoutResponse{
-		PayPalResponse: *resp,
-		Token:          resp.Values.Get("TOKEN"),
-	}, nil
+	r := &PayPalSetExpressCheckoutResponse{PayPalResponse: *resp}
+	if err := r.unmarshal(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// addPaymentRequests checks requests and adds them to values as the
+// PAYMENTREQUEST_n_ sections.
+func addPaymentRequests(values url.Values, requests []PaymentRequest) error {
+	if len(requests) == 0 || len(requests) > MAX_PAYMENT_REQUESTS {
+		return &ValidationError{
+			Field:  "PAYMENTREQUEST",
+			Value:  strconv.Itoa(len(requests)),
+			Reason: fmt.Sprintf("a checkout needs 1 to %d payment requests", MAX_PAYMENT_REQUESTS),
+		}
+	}
+
+	payments := struct {
+		Sections []PaymentRequest `nvp:"PAYMENTREQUEST,section"`
+	}{make([]PaymentRequest, len(requests))}
+	paymentRequestIds := make(map[string]bool, len(requests))
+	for n, request := range requests {
+		amounts := []Money{
+			{Currency: request.CurrencyCode}, request.Amount, request.ItemAmount, request.ShippingAmount,
+			request.InsuranceAmount, request.ShippingDiscount, request.HandlingAmount, request.TaxAmount,
+		}
+		for _, item := range request.Items {
+			amounts = append(amounts, item.Amount, item.TaxAmount)
+		}
+		currency, err := sameCurrency(amounts...)
+		if err != nil {
+			return err
+		}
+
+		// amounts given without a currency are formatted and checked in the
+		// request's, so 1000 JPY isn't sent as 10.00; zero amounts are left
+		// alone where omitempty should still skip them
+		request.Amount.Currency = currency
+		for _, amount := range []*Money{
+			&request.ItemAmount, &request.ShippingAmount, &request.InsuranceAmount,
+			&request.ShippingDiscount, &request.HandlingAmount, &request.TaxAmount,
+		} {
+			if !amount.IsZero() {
+				amount.Currency = currency
+			}
+		}
+		request.Items = append([]PaymentItem(nil), request.Items...)
+		for i := range request.Items {
+			request.Items[i].Amount.Currency = currency
+			if !request.Items[i].TaxAmount.IsZero() {
+				request.Items[i].TaxAmount.Currency = currency
+			}
+		}
+
+		// parallel payments are told apart by their ids
+		prefix := fmt.Sprintf("PAYMENTREQUEST_%d_", n)
+		if len(requests) > 1 {
+			if len(request.PaymentRequestId) == 0 || paymentRequestIds[request.PaymentRequestId] {
+				return &ValidationError{Field: prefix + "PAYMENTREQUESTID", Value: request.PaymentRequestId, Reason: "parallel payments need unique payment request ids"}
+			}
+			if len(request.SellerPayPalAccountId) == 0 {
+				return &ValidationError{Field: prefix + "SELLERPAYPALACCOUNTID", Value: request.SellerPayPalAccountId, Reason: "parallel payments need a seller"}
+			}
+			paymentRequestIds[request.PaymentRequestId] = true
+		}
+
+		// only the discount is negative; item amounts may be too, for
+		// discounts given as items
+		keys := []string{"AMT", "ITEMAMT", "SHIPPINGAMT", "INSURANCEAMT", "HANDLINGAMT", "TAXAMT"}
+		for i, amount := range []Money{
+			request.Amount, request.ItemAmount, request.ShippingAmount,
+			request.InsuranceAmount, request.HandlingAmount, request.TaxAmount,
+		} {
+			if err := ValidateAmount(prefix+keys[i], amount); err != nil {
+				return err
+			}
+		}
+		if !request.ShippingDiscount.IsNegative() {
+			request.ShippingDiscount.Minor = -request.ShippingDiscount.Minor
+		}
+		payments.Sections[n] = request
+	}
+
+	return marshalInto(values, &payments)
+}
+
+// marshalInto encodes v into values, reporting an invalid amount as a
+// *ValidationError for its NVP key like addAmount does.
+func marshalInto(values url.Values, v interface{}) error {
+	err := nvp.MarshalInto(values, v)
+	var fieldError *nvp.FieldError
+	var validationError *ValidationError
+	if errors.As(err, &fieldError) && errors.As(fieldError.Err, &validationError) {
+		return &ValidationError{Field: fieldError.Key, Value: validationError.Value, Reason: validationError.Reason}
+	}
+	return err
 }
 
 func (pClient *PayPalClient) CreateBillingAgreement(token string) (*PayPalBillingAgreementResponse, error) {
+	return pClient.CreateBillingAgreementContext(context.Background(), token)
+}
+
+func (pClient *PayPalClient) CreateBillingAgreementContext(ctx context.Context, token string) (*PayPalBillingAgreementResponse, error) {
 	values := url.Values{}
 	values.Set("METHOD", "CreateBillingAgreement")
 	values.Add("TOKEN", token)
 
-	resp, err := pClient.PerformRequest(values)
+	resp, err := pClient.PerformRequestContext(ctx, values)
 	if err != nil {
 		return nil, err
 	}
 
-	return &PayPalBillingAgreementResponse{
-		PayPalResponse:     *resp,
-		BillingAgreementId: resp.Values.Get("BILLINGAGREEMENTID"),
-	}, nil
+	r := &PayPalBillingAgreementResponse{PayPalResponse: *resp}
+	if err := r.unmarshal(r); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 func (pClient *PayPalClient) GetExpressCheckoutDetails(token string) (*PayPalExpressCheckoutDetails, error) {
+	return pClient.GetExpressCheckoutDetailsContext(context.Background(), token)
+}
+
+func (pClient *PayPalClient) GetExpressCheckoutDetailsContext(ctx context.Context, token string) (*PayPalExpressCheckoutDetails, error) {
 	values := url.Values{}
 	values.Set("METHOD", "GetExpressCheckoutDetails")
 	values.Add("TOKEN", token)
 
-	resp, err := pClient.PerformRequest(values)
+	resp, err := pClient.PerformRequestContext(ctx, values)
 	if err != nil {
 		return nil, err
 	}
 
-	// PaymentsInfo                   []PaymentInfo
-
-	r := &PayPalExpressCheckoutDetails{
-		PayPalResponse: *resp,
-		Token:          resp.Values.Get("TOKEN"),
-		PhoneNumber:    resp.Values.Get("PHONENUM"),
-		CheckoutStatus: resp.Values.Get("CHECKOUTSTATUS"),
-		PayerID:        resp.Values.Get("PAYERID"),
-		Email:          resp.Values.Get("EMAIL"),
-		FirstName:      resp.Values.Get("FIRSTNAME"),
-		LastName:       resp.Values.Get("LASTNAME"),
-		CountryCode:    resp.Values.Get("COUNTRYCODE"),
+	r := &PayPalExpressCheckoutDetails{PayPalResponse: *resp}
+	// the PAYMENTREQUEST_n_ sections also hold the TRANSACTIONID, amounts and
+	// status of payments already made
+	var payments struct {
+		Sections []PaymentInfo `nvp:"PAYMENTREQUEST,section"`
 	}
+	if err := r.unmarshal(r, &payments); err != nil {
+		return nil, err
+	}
+	r.PayerStatusVerified = resp.Values.Get("PAYERSTATUS") == "verified"
 
-	if resp.Values.Get("BILLINGAGREEMENTACCEPTEDSTATUS") == "1" {
-		r.BillingAgreementAccepted = true
+	for n, request := range r.PaymentRequests {
+		payment := PaymentInfo{}
+		if n < len(payments.Sections) {
+			payment = payments.Sections[n]
+		}
+		payment.parseOrderTime(fmt.Sprintf("PAYMENTREQUEST_%d_", n))
+		r.ShippingAddresses = append(r.ShippingAddresses, request.ShippingAddress)
+		r.PaymentsInfo = append(r.PaymentsInfo, payment)
 	}
 
-	if resp.Values.Get("PAYERSTATUS") == "verified" {
-		r.PayerStatusVerified = true
+	return r, nil
+}
+
+// Deprecated: use DoExpressCheckoutSaleContext, which takes a Money amount.
+func (pClient *PayPalClient) DoExpressCheckoutSale(token, payerID, currencyCode string, finalPaymentAmount float64) (*PayPalExpressPaymentResponse, error) {
+	amount, err := exactMoneyFromFloat("PAYMENTREQUEST_0_AMT", finalPaymentAmount, currencyCode)
+	if err != nil {
+		return nil, err
 	}
+	return pClient.DoExpressCheckoutSaleContext(context.Background(), token, payerID, amount)
+}
 
-	r.ShippingAddresses = make([]AddressInfo, 10)
-	for i := 0; i < 10; i++ {
-		idx := fmt.Sprintf("%d", i)
-		address := AddressInfo{
-			Name:              resp.Values.Get("PAYMENTREQUEST_" + idx + "_SHIPTONAME"),
-			Street:            resp.Values.Get("PAYMENTREQUEST_" + idx + "_SHIPTOSTREET"),
-			Street2:           resp.Values.Get("PAYMENTREQUEST_" + idx + "_SHIPTOSTREET2"),
-			City:              resp.Values.Get("PAYMENTREQUEST_" + idx + "_SHIPTOCITY"),
-			State:             resp.Values.Get("PAYMENTREQUEST_" + idx + "_SHIPTOSTATE"),
-			Zip:               resp.Values.Get("PAYMENTREQUEST_" + idx + "_SHIPTOZIP"),
-			CountryCode:       resp.Values.Get("PAYMENTREQUEST_" + idx + "_SHIPTOCOUNTRYCODE"),
-			Country:           resp.Values.Get("PAYMENTREQUEST_" + idx + "_SHIPTOCOUNTRYNAME"),
-			PhoneNumber:       resp.Values.Get("PAYMENTREQUEST_" + idx + "_SHIPTOPHONENUM"),
-			Status:            resp.Values.Get("PAYMENTREQUEST_" + idx + "_ADDRESSSTATUS"),
-			NormatilzedStatus: resp.Values.Get("PAYMENTREQUEST_" + idx + "_ADDRESSNORMALIZATIONSTATUS"),
-		}
-		r.ShippingAddresses = append(r.ShippingAddresses, address)
+func (pClient *PayPalClient) DoExpressCheckoutSaleContext(ctx context.Context, token, payerID string, finalPaymentAmount Money) (*PayPalExpressPaymentResponse, error) {
+	return pClient.DoExpressCheckoutPaymentContext(ctx, token, payerID, "Sale", finalPaymentAmount)
+}
+
+// paymentType can be "Sale" or "Authorization" or "Order" (ship later)
+//
+// Deprecated: use DoExpressCheckoutPaymentContext, which takes a Money amount.
+func (pClient *PayPalClient) DoExpressCheckoutPayment(token, payerID, paymentType, currencyCode string, finalPaymentAmount float64) (*PayPalExpressPaymentResponse, error) {
+	amount, err := exactMoneyFromFloat("PAYMENTREQUEST_0_AMT", finalPaymentAmount, currencyCode)
+	if err != nil {
+		return nil, err
 	}
+	return pClient.DoExpressCheckoutPaymentContext(context.Background(), token, payerID, paymentType, amount)
+}
 
-	return r, nil
+// paymentType can be "Sale" or "Authorization" or "Order" (ship later)
+func (pClient *PayPalClient) DoExpressCheckoutPaymentContext(ctx context.Context, token, payerID, paymentType string, finalPaymentAmount Money) (*PayPalExpressPaymentResponse, error) {
+	return pClient.DoExpressCheckoutPaymentsContext(ctx, token, payerID, []PaymentRequest{
+		{Amount: finalPaymentAmount, PaymentAction: paymentType},
+	})
+}
+
+func (pClient *PayPalClient) DoExpressCheckoutPayments(token, payerID string, requests []PaymentRequest) (*PayPalExpressPaymentResponse, error) {
+	return pClient.DoExpressCheckoutPaymentsContext(context.Background(), token, payerID, requests)
 }
 
-// // paymentType can be "Sale" or "Authorization" or "Order" (ship later)
-// func (pClient *PayPalClient) DoExpressCheckoutPayment(token, payerID, paymentType, currencyCode string, finalPaymentAmount float64) (*PayPalExpressPaymentResponse, error) {
-// 	values := url.Values{}
-// 	values.Set("METHOD", "DoExpressCheckoutPayment")
-// 	values.Add("TOKEN", token)
-// 	values.Add("PAYERID", payerID)
-// 	values.Add("PAYMENTREQUEST_0_PAYMENTACTION", paymentType)
-// 	values.Add("PAYMENTREQUEST_0_CURRENCYCODE", currencyCode)
-// 	values.Add("PAYMENTREQUEST_0_AMT", fmt.Sprintf("%.2f", finalPaymentAmount))
+// DoExpressCheckoutPaymentsContext completes a checkout with the payment
+// requests it was set up with, which for parallel payments must carry the
+// same PaymentRequestId and SellerPayPalAccountId. The response has a
+// PaymentInfo and SellerInfo per request that was paid.
+func (pClient *PayPalClient) DoExpressCheckoutPaymentsContext(ctx context.Context, token, payerID string, requests []PaymentRequest) (*PayPalExpressPaymentResponse, error) {
+	values := url.Values{}
+	values.Set("METHOD", "DoExpressCheckoutPayment")
+	values.Add("TOKEN", token)
+	values.Add("PAYERID", payerID)
+	if err := addPaymentRequests(values, requests); err != nil {
+		return nil, err
+	}
+
+	// a partial success is returned along with its error, since some of the
+	// sellers were paid
+	resp, err := pClient.PerformRequestContext(ctx, values)
+	if resp == nil || (err != nil && !resp.IsPartialSuccess()) {
+		return nil, err
+	}
+
+	r := &PayPalExpressPaymentResponse{PayPalResponse: *resp}
+	// each PAYMENTINFO_n_ section describes both the payment and its seller
+	var payments struct {
+		Sections []struct {
+			PaymentInfo
+			SellerInfo
+		} `nvp:"PAYMENTINFO,section"`
+	}
+	if err := r.unmarshal(r, &payments); err != nil {
+		return nil, err
+	}
+
+	for n, payment := range payments.Sections {
+		prefix := fmt.Sprintf("PAYMENTINFO_%d_", n)
+		payment.parseOrderTime(prefix)
+		r.PaymentsInfo = append(r.PaymentsInfo, payment.PaymentInfo)
+		r.Sellers = append(r.Sellers, payment.SellerInfo)
+
+		for _, action := range []string{"Pending", "Report", "Deny"} {
+			for i := 0; ; i++ {
+				key := fmt.Sprintf("L_%sFMF%sID%d", prefix, strings.ToUpper(action), i)
+				id := resp.Values.Get(key)
+				if len(id) == 0 {
+					break
+				}
+				r.FraudFilters = append(r.FraudFilters, FraudFilter{
+					PaymentRequest: n,
+					Action:         action,
+					Id:             id,
+					Name:           resp.Values.Get(fmt.Sprintf("L_%sFMF%sNAME%d", prefix, strings.ToUpper(action), i)),
+				})
+			}
+		}
+	}
+
+	for _, entry := range parseErrors(resp.Values) {
+		if entry.PaymentRequest >= 0 {
+			r.PaymentErrors = append(r.PaymentErrors, entry)
+		}
+	}
 
-// 	return pClient.PerformRequest(values)
-// }
+	return r, err
+}
 
 // Note that the billingAgreementId must be URL-decoded
+//
+// Deprecated: use DoReferenceTransactionContext, which takes a Money amount.
 func (pClient *PayPalClient) DoReferenceTransaction(billingAgreementId, paymentType string, finalPaymentAmount float64) (*PayPalReferenceTransactionResponse, error) {
+	amount, err := exactMoneyFromFloat("AMT", finalPaymentAmount, "USD")
+	if err != nil {
+		return nil, err
+	}
+	return pClient.DoReferenceTransactionContext(context.Background(), billingAgreementId, paymentType, amount)
+}
+
+// Note that the billingAgreementId must be URL-decoded
+func (pClient *PayPalClient) DoReferenceTransactionContext(ctx context.Context, billingAgreementId, paymentType string, finalPaymentAmount Money) (*PayPalReferenceTransactionResponse, error) {
 	values := url.Values{}
 	values.Set("METHOD", "DoReferenceTransaction")
 	values.Add("REFERENCEID", billingAgreementId)
 	values.Add("PAYMENTACTION", paymentType)
-	values.Add("AMT", fmt.Sprintf("%.2f", finalPaymentAmount))
+	if err := addAmount(values, "AMT", finalPaymentAmount); err != nil {
+		return nil, err
+	}
+	if len(finalPaymentAmount.Currency) != 0 {
+		values.Add("CURRENCYCODE", finalPaymentAmount.Currency)
+	}
 
-	resp, err := pClient.PerformRequest(values)
+	resp, err := pClient.PerformRequestContext(ctx, values)
 	if err != nil {
 		return nil, err
 	}
 
-	amt, _ := strconv.ParseFloat(resp.Values.Get("AMT"), 64)
-	feeAmt, _ := strconv.ParseFloat(resp.Values.Get("FEEAMT"), 64)
-	settleAmt, _ := strconv.ParseFloat(resp.Values.Get("SETTLEAMT"), 64)
-	taxAmt, _ := strconv.ParseFloat(resp.Values.Get("TAXAMT"), 64)
-	exchangeRate, _ := strconv.ParseFloat(resp.Values.Get("EXCHANGERATE"), 64)
-
-	instrumentCategory, _ := strconv.Atoi(resp.Values.Get("INSTRUMENTCATEGORY"))
-
-	protectionEligibilityTypes := strings.Split(resp.Values.Get("PROTECTIONELIGIBILITYTYPE"), ",")
-
-	return &PayPalReferenceTransactionResponse{
-		PayPalResponse:     *resp,
-		AvsCode:            resp.Values.Get("AVSCODE"),
-		Cvv2Match:          resp.Values.Get("CVV2MATCH"),
-		BillingAgreementId: resp.Values.Get("BILLINGAGREEMENTID"),
-		PaymentAdviceCode:  resp.Values.Get("PAYMENTADVICECODE"),
-		MsgSubId:           resp.Values.Get("MSGSUBID"),
-		PaymentInfo: PaymentInfo{
-			TransactionId:             resp.Values.Get("TRANSACTIONID"),
-			ParentTransactionId:       resp.Values.Get("PARENTTRANSACTIONID"),
-			ReceiptId:                 resp.Values.Get("RECEIPTID"),
-			TransactionType:           resp.Values.Get("TRANSACTIONTYPE"),
-			PaymentType:               resp.Values.Get("PAYMENTTYPE"),
-			OrderTime:                 resp.Values.Get("ORDERTIME"),
-			Amount:                    amt,
-			CurrencyCode:              resp.Values.Get("CURRENCYCODE"),
-			FeeAmount:                 feeAmt,
-			SettleAmount:              settleAmt,
-			TaxAmount:                 taxAmt,
-			ExchangeRate:              exchangeRate,
-			PaymentStatus:             resp.Values.Get("PAYMENTSTATUS"),
-			PendingReason:             resp.Values.Get("PENDINGREASON"),
-			ReasonCode:                resp.Values.Get("REASONCODE"),
-			ProtectionEligibility:     resp.Values.Get("PROTECTIONELIGIBILITY"),
-			ProtectionEligibilityType: protectionEligibilityTypes,
-			StoreId:                   resp.Values.Get("STOREID"),
-			TerminalId:                resp.Values.Get("TERMINALID"),
-			InstrumentCategory:        instrumentCategory,
-			InstrumentId:              resp.Values.Get("INSTRUMENTID"),
-		},
-	}, nil
+	r := &PayPalReferenceTransactionResponse{PayPalResponse: *resp}
+	if err := r.unmarshal(r); err != nil {
+		return nil, err
+	}
+	r.parseOrderTime("")
+	return r, nil
+}
+
+func copyValues(values url.Values) url.Values {
+	copied := make(url.Values, len(values)+4)
+	for key, value := range values {
+		copied[key] = append([]string(nil), value...)
+	}
+	return copied
 }
 
 // Point-of-Sale transactions not supported currently
+//
+// Deprecated: use RefundTransactionContext, which takes Money amounts.
 func (pClient *PayPalClient) RefundTransaction(refundAmount, shippingAmount, taxAmount float64, transactionId, invoiceId, msgSubId, currencyCode string, partialRefund bool) (*PayPalRefundTransactionResponse, error) {
+	refund, err := exactMoneyFromFloat("AMT", refundAmount, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	shipping, err := exactMoneyFromFloat("SHIPPINGAMT", shippingAmount, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	tax, err := exactMoneyFromFloat("TAXAMT", taxAmount, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	return pClient.RefundTransactionContext(context.Background(), refund, shipping, tax, transactionId, invoiceId, msgSubId, partialRefund)
+}
+
+// Point-of-Sale transactions not supported currently
+func (pClient *PayPalClient) RefundTransactionContext(ctx context.Context, refundAmount, shippingAmount, taxAmount Money, transactionId, invoiceId, msgSubId string, partialRefund bool) (*PayPalRefundTransactionResponse, error) {
+	currencyCode, err := sameCurrency(refundAmount, shippingAmount, taxAmount)
+	if err != nil {
+		return nil, err
+	}
+
 	values := url.Values{}
 	values.Set("METHOD", "RefundTransaction")
 	values.Add("TRANSACTIONID", transactionId)
 	values.Add("INVOICEID", invoiceId)
-	values.Add("SHIPPINGAMT", fmt.Sprintf("%.2f", shippingAmount))
-	values.Add("TAXAMT", fmt.Sprintf("%.2f", taxAmount))
-	values.Add("MSGSUBID", msgSubId)
+	if err := addAmount(values, "SHIPPINGAMT", shippingAmount); err != nil {
+		return nil, err
+	}
+	if err := addAmount(values, "TAXAMT", taxAmount); err != nil {
+		return nil, err
+	}
+	if len(msgSubId) != 0 {
+		values.Add("MSGSUBID", msgSubId)
+	}
 
 	refundType := "Full"
 	if partialRefund {
@@ -465,41 +975,45 @@ func (pClient *PayPalClient) RefundTransaction(refundAmount, shippingAmount, tax
 	}
 
 	if partialRefund {
-		values.Add("AMT", fmt.Sprintf("%.2f", refundAmount))
+		if err := addAmount(values, "AMT", refundAmount); err != nil {
+			return nil, err
+		}
 	}
 
-	resp, err := pClient.PerformRequest(values)
+	resp, err := pClient.PerformRequestContext(ctx, values)
 	if err != nil {
 		return nil, err
 	}
 
-	refundFee, _ := strconv.ParseFloat(resp.Values.Get("FEEREFUNDAMT"), 64)
-	netRefund, _ := strconv.ParseFloat(resp.Values.Get("NETREFUNDAMT"), 64)
-	grossRefund, _ := strconv.ParseFloat(resp.Values.Get("GROSSREFUNDAMT"), 64)
-	totalRefund, _ := strconv.ParseFloat(resp.Values.Get("TOTALREFUNDAMT"), 64)
-
-	return &PayPalRefundTransactionResponse{
-		PayPalResponse:      *resp,
-		RefundTransactionId: resp.Values.Get("REFUNDTRANSACTIONID"),
-		RefundFeeAmount:     refundFee,
-		NetRefundAmount:     netRefund,
-		GrossRefundAmount:   grossRefund,
-		TotalRefundAmount:   totalRefund,
-		CurrencyCode:        resp.Values.Get("CURRENCYCODE"),
-		RefundStatus:        resp.Values.Get("REFUNDSTATUS"),
-		PendingReason:       resp.Values.Get("PENDINGREASON"),
-		MsgSubId:            resp.Values.Get("MSGSUBID"),
-	}, nil
+	r := &PayPalRefundTransactionResponse{PayPalResponse: *resp}
+	if err := r.unmarshal(r); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 // MassPay only returns a standard response
 // Only supports one transaction per request currently
+//
+// Deprecated: use MassPayContext, which takes a Money amount.
 func (pClient *PayPalClient) MassPay(paymentAmount float64, emailSubject, currencyCode, trackingId, note, receiverType, identifier string) (*PayPalResponse, error) {
+	amount, err := exactMoneyFromFloat("L_AMT0", paymentAmount, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	return pClient.MassPayContext(context.Background(), amount, emailSubject, trackingId, note, receiverType, identifier)
+}
+
+// MassPay only returns a standard response
+// Only supports one transaction per request currently
+func (pClient *PayPalClient) MassPayContext(ctx context.Context, paymentAmount Money, emailSubject, trackingId, note, receiverType, identifier string) (*PayPalResponse, error) {
 	values := url.Values{}
 	values.Set("METHOD", "MassPay")
 	values.Add("EMAILSUBJECT", emailSubject)
-	values.Add("CURRENCYCODE", currencyCode)
-	values.Add("L_AMT0", fmt.Sprintf("%.2f", paymentAmount))
+	values.Add("CURRENCYCODE", paymentAmount.Currency)
+	if err := addAmount(values, "L_AMT0", paymentAmount); err != nil {
+		return nil, err
+	}
 	values.Add("L_UNIQUEID0", trackingId)
 	values.Add("L_NOTE0", note)
 
@@ -518,5 +1032,5 @@ func (pClient *PayPalClient) MassPay(paymentAmount float64, emailSubject, curren
 
 	values.Add("RECEIVERTYPE", receiverType)
 
-	return pClient.PerformRequest(values)
+	return pClient.PerformRequestContext(ctx, values)
 }
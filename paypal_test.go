@@ -1,7 +1,7 @@
 package paypal_test
 
 import (
-  "../paypal"
+  "github.com/crowdmob/paypal"
 	"testing"
 	"os"
   "strings"
@@ -36,7 +36,7 @@ func TestSandboxRedirect(t *testing.T) {
 	// Make a array of your digital-goods
 	testGoods := []paypal.PayPalDigitalGood{paypal.PayPalDigitalGood{
     Name: "Test Good", 
-    Amount: 200.000,
+    Amount: paypal.NewMoney(20000, "USD"),
     Quantity: 5,
   }}
   
@@ -60,8 +60,8 @@ func TestSandboxRedirect(t *testing.T) {
     t.Errorf("Didn't get ACK=Success back from PayPal. Response was: %#v", response.Values)
   }
   
-  if strings.Index(response.CheckoutUrl(), response.Values["TOKEN"][0]) < 0 {
-    t.Errorf("Couldnt find TOKEN in response.CheckoutUrl(). response.CheckoutUrl() was: %s when token was: %s", response.CheckoutUrl(),response.Values["TOKEN"][0]) 
+  if strings.Index(response.GetCheckoutUrl(), response.Values["TOKEN"][0]) < 0 {
+    t.Errorf("Couldnt find TOKEN in response.GetCheckoutUrl(). response.GetCheckoutUrl() was: %s when token was: %s", response.GetCheckoutUrl(),response.Values["TOKEN"][0]) 
   }
 }
 
@@ -0,0 +1,142 @@
+package paypal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by clients whose rate limit fails fast when the
+// request budget is exhausted.
+var ErrRateLimited = errors.New("paypal: client-side rate limit exceeded")
+
+// RateLimit caps how fast and how many requests at once a client sends, so
+// that bursts are smoothed out before PayPal starts throttling them. Retries
+// count as requests.
+type RateLimit struct {
+	Rate        float64 // requests per second, 0 for no limit
+	Burst       int     // requests that may be sent at once after a quiet period, at least 1
+	MaxInFlight int     // concurrent requests, 0 for no limit
+	FailFast    bool    // return ErrRateLimited instead of waiting for the budget
+}
+
+// WithRateLimit limits every request of the client, except for methods given
+// their own limit with WithMethodRateLimit. Clients derived with OnBehalfOf
+// share the limit.
+func WithRateLimit(limit RateLimit) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.rateLimiter = newRateLimiter(limit)
+	}
+}
+
+// WithMethodRateLimit limits the requests for one NVP method, e.g.
+// "RefundTransaction", separately from the client's other requests.
+func WithMethodRateLimit(method string, limit RateLimit) ClientOption {
+	return func(pClient *PayPalClient) {
+		if pClient.methodRateLimiters == nil {
+			pClient.methodRateLimiters = make(map[string]*rateLimiter)
+		}
+		pClient.methodRateLimiters[method] = newRateLimiter(limit)
+	}
+}
+
+type rateLimiter struct {
+	limit    RateLimit
+	inFlight chan struct{}
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	limiter := &rateLimiter{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+	if limit.MaxInFlight > 0 {
+		limiter.inFlight = make(chan struct{}, limit.MaxInFlight)
+	}
+	return limiter
+}
+
+// acquireRequest waits for the rate limit of method, returning a function
+// that must be called once the request is done.
+func (pClient *PayPalClient) acquireRequest(ctx context.Context, method string) (func(), error) {
+	limiter := pClient.methodRateLimiters[method]
+	if limiter == nil {
+		limiter = pClient.rateLimiter
+	}
+	if limiter == nil {
+		return func() {}, nil
+	}
+	return limiter.acquire(ctx)
+}
+
+func (l *rateLimiter) acquire(ctx context.Context) (func(), error) {
+	if l.inFlight != nil {
+		if l.limit.FailFast {
+			select {
+			case l.inFlight <- struct{}{}:
+			default:
+				return nil, ErrRateLimited
+			}
+		} else {
+			select {
+			case l.inFlight <- struct{}{}:
+			case <-ctx.Done():
+				return nil, contextError(ctx, ctx.Err())
+			}
+		}
+	}
+	release := func() {
+		if l.inFlight != nil {
+			<-l.inFlight
+		}
+	}
+
+	if err := l.take(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// take removes a token from the bucket, waiting for one to be added unless
+// the limit fails fast.
+func (l *rateLimiter) take(ctx context.Context) error {
+	if l.limit.Rate <= 0 {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.limit.Rate
+		if l.tokens > float64(l.limit.Burst) {
+			l.tokens = float64(l.limit.Burst)
+		}
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.limit.Rate * float64(time.Second))
+		l.mu.Unlock()
+
+		if l.limit.FailFast {
+			return ErrRateLimited
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return contextError(ctx, ctx.Err())
+		}
+	}
+}
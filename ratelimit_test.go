@@ -0,0 +1,94 @@
+package paypal_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal"
+)
+
+func TestRateLimitFailFast(t *testing.T) {
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ACK=Success"))
+	}, paypal.WithRateLimit(paypal.RateLimit{Rate: 0.001, Burst: 2, FailFast: true}),
+		paypal.WithMethodRateLimit("RefundTransaction", paypal.RateLimit{Rate: 0.001, Burst: 1, FailFast: true}))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+			t.Fatalf("Expected request %d to be within the burst, got %v", i, err)
+		}
+	}
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); !errors.Is(err, paypal.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+
+	refund := paypal.NewMoney(100, "USD")
+	if _, err := client.RefundTransactionContext(t.Context(), refund, paypal.Money{}, paypal.Money{}, "TX", "", "", true); err != nil {
+		t.Errorf("Expected RefundTransaction to have its own limit, got %v", err)
+	}
+	if _, err := client.RefundTransactionContext(t.Context(), refund, paypal.Money{}, paypal.Money{}, "TX", "", "", true); !errors.Is(err, paypal.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestRateLimitWaits(t *testing.T) {
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ACK=Success"))
+	}, paypal.WithRateLimit(paypal.RateLimit{Rate: 20, Burst: 1}))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected 3 requests at 20/s to take about 100ms, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond)
+	defer cancel()
+	if _, err := client.GetExpressCheckoutDetailsContext(ctx, "EC-123"); !errors.Is(err, paypal.ErrDeadlineExceeded) {
+		t.Errorf("Expected waiting for the limit to respect the deadline, got %v", err)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write([]byte("ACK=Success"))
+	}, paypal.WithRateLimit(paypal.RateLimit{MaxInFlight: 2}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetExpressCheckoutDetailsContext(context.Background(), "EC-123"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 requests in flight, got %d", maxInFlight)
+	}
+}
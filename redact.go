@@ -0,0 +1,223 @@
+package paypal
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const REDACTED = "[REDACTED]"
+
+// redactors for NVP keys, after stripping list indexes and payment request
+// prefixes (L_EMAIL0 and PAYMENTREQUEST_0_EMAIL are both EMAIL)
+var valueRedactors = map[string]func(string) string{
+	"USER":      redactAll,
+	"PWD":       redactAll,
+	"SIGNATURE": redactAll,
+	"CVV2":      redactAll,
+	"ACCT":      redactAllButLast4,
+	"TOKEN":     redactAllButLast4,
+	"EMAIL":     redactEmail,
+
+	"BUYERMARKETINGEMAIL": redactEmail,
+}
+
+// redactors for struct fields of responses
+var fieldRedactors = map[string]func(string) string{
+	"Token": redactAllButLast4,
+	"Email": redactEmail,
+
+	"BuyerMarketingEmail": redactEmail,
+}
+
+// RedactedValues prints NVP values with credentials, tokens, card numbers and
+// email addresses masked:
+//
+//	log.Printf("sending %v", paypal.RedactedValues(values))
+type RedactedValues url.Values
+
+func (v RedactedValues) String() string {
+	return url.Values(v.redacted()).Encode()
+}
+
+func (v RedactedValues) GoString() string {
+	return fmt.Sprintf("%#v", url.Values(v.redacted()))
+}
+
+func (v RedactedValues) LogValue() slog.Value {
+	redacted := v.redacted()
+	keys := make([]string, 0, len(redacted))
+	for key := range redacted {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, len(keys))
+	for i, key := range keys {
+		attrs[i] = slog.String(key, strings.Join(redacted[key], ","))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func (v RedactedValues) redacted() RedactedValues {
+	if v == nil {
+		return nil
+	}
+	redacted := make(RedactedValues, len(v))
+	for key, values := range v {
+		redactor := valueRedactors[baseKey(key)]
+		copied := make([]string, len(values))
+		for i, value := range values {
+			if redactor != nil && len(value) != 0 {
+				value = redactor(value)
+			}
+			copied[i] = value
+		}
+		redacted[key] = copied
+	}
+	return redacted
+}
+
+// baseKey strips the L_ prefix, PAYMENTREQUEST_n_/PAYMENTINFO_n_ prefixes and
+// trailing list index from an NVP key.
+func baseKey(key string) string {
+	key = strings.TrimPrefix(key, "L_")
+	for _, section := range []string{"PAYMENTREQUEST_", "PAYMENTINFO_"} {
+		if rest, ok := strings.CutPrefix(key, section); ok {
+			if i := strings.IndexByte(rest, '_'); i >= 0 {
+				key = rest[i+1:]
+			}
+		}
+	}
+	return strings.TrimRight(key, "0123456789")
+}
+
+func redactAll(string) string {
+	return REDACTED
+}
+
+func redactAllButLast4(value string) string {
+	if len(value) <= 4 {
+		return REDACTED
+	}
+	return strings.Repeat("*", len(value)-4) + value[len(value)-4:]
+}
+
+func redactEmail(value string) string {
+	at := strings.LastIndexByte(value, '@')
+	if at <= 0 {
+		return REDACTED
+	}
+	return value[:1] + "***" + value[at:]
+}
+
+// redactedCopy returns a copy of the struct v points to, with the fields in
+// fieldRedactors masked and url.Values replaced by redacted copies. It is a
+// value rather than a pointer so that printing it doesn't call back into the
+// pointer-receiver String and GoString methods.
+func redactedCopy(v interface{}) interface{} {
+	copied := reflect.New(reflect.TypeOf(v).Elem()).Elem()
+	copied.Set(reflect.ValueOf(v).Elem())
+	redactStruct(copied)
+	return copied.Interface()
+}
+
+var urlValuesType = reflect.TypeOf(url.Values{})
+
+func redactStruct(s reflect.Value) {
+	for i := 0; i < s.NumField(); i++ {
+		field := s.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+
+		switch {
+		case field.Type() == urlValuesType:
+			field.Set(reflect.ValueOf(url.Values(RedactedValues(field.Interface().(url.Values)).redacted())))
+		case field.Kind() == reflect.Struct:
+			redactStruct(field)
+		case field.Kind() == reflect.String:
+			if redactor := fieldRedactors[s.Type().Field(i).Name]; redactor != nil && field.Len() != 0 {
+				field.SetString(redactor(field.String()))
+			}
+		}
+	}
+}
+
+func redactedString(v interface{}) string {
+	return fmt.Sprintf("%+v", redactedCopy(v))
+}
+
+func redactedGoString(v interface{}) string {
+	return fmt.Sprintf("%#v", redactedCopy(v))
+}
+
+func redactedLogValue(v interface{}) slog.Value {
+	return slog.AnyValue(redactedCopy(v))
+}
+
+func (r *PayPalResponse) String() string       { return redactedString(r) }
+func (r *PayPalResponse) GoString() string     { return redactedGoString(r) }
+func (r *PayPalResponse) LogValue() slog.Value { return redactedLogValue(r) }
+
+func (r *PayPalSetExpressCheckoutResponse) String() string       { return redactedString(r) }
+func (r *PayPalSetExpressCheckoutResponse) GoString() string     { return redactedGoString(r) }
+func (r *PayPalSetExpressCheckoutResponse) LogValue() slog.Value { return redactedLogValue(r) }
+
+func (r *PayPalBillingAgreementResponse) String() string       { return redactedString(r) }
+func (r *PayPalBillingAgreementResponse) GoString() string     { return redactedGoString(r) }
+func (r *PayPalBillingAgreementResponse) LogValue() slog.Value { return redactedLogValue(r) }
+
+func (r *PayPalExpressCheckoutDetails) String() string       { return redactedString(r) }
+func (r *PayPalExpressCheckoutDetails) GoString() string     { return redactedGoString(r) }
+func (r *PayPalExpressCheckoutDetails) LogValue() slog.Value { return redactedLogValue(r) }
+
+func (r *PayPalExpressPaymentResponse) String() string       { return redactedString(r) }
+func (r *PayPalExpressPaymentResponse) GoString() string     { return redactedGoString(r) }
+func (r *PayPalExpressPaymentResponse) LogValue() slog.Value { return redactedLogValue(r) }
+
+func (r *PayPalReferenceTransactionResponse) String() string       { return redactedString(r) }
+func (r *PayPalReferenceTransactionResponse) GoString() string     { return redactedGoString(r) }
+func (r *PayPalReferenceTransactionResponse) LogValue() slog.Value { return redactedLogValue(r) }
+
+func (r *PayPalRefundTransactionResponse) String() string       { return redactedString(r) }
+func (r *PayPalRefundTransactionResponse) GoString() string     { return redactedGoString(r) }
+func (r *PayPalRefundTransactionResponse) LogValue() slog.Value { return redactedLogValue(r) }
+
+func (pClient *PayPalClient) String() string {
+	return fmt.Sprintf("PayPalClient{endpoint: %s, subject: %s, credentials: %s}", pClient.endpoint, pClient.subject, REDACTED)
+}
+
+func (pClient *PayPalClient) GoString() string {
+	return fmt.Sprintf("&paypal.PayPalClient{endpoint:%q, usesSandbox:%t, subject:%q, credentials:%q}", pClient.endpoint, pClient.usesSandbox, pClient.subject, REDACTED)
+}
+
+func (pClient *PayPalClient) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("endpoint", pClient.endpoint),
+		slog.Bool("sandbox", pClient.usesSandbox),
+		slog.String("subject", pClient.subject),
+	)
+}
+
+func (c Credentials) String() string {
+	return fmt.Sprintf("{Username:%s Password:%s Signature:%s}", REDACTED, REDACTED, REDACTED)
+}
+
+func (c Credentials) GoString() string {
+	return fmt.Sprintf("paypal.Credentials{Username:%q, Password:%q, Signature:%q}", REDACTED, REDACTED, REDACTED)
+}
+
+func (c StaticCredentials) String() string   { return Credentials(c).String() }
+func (c StaticCredentials) GoString() string { return Credentials(c).GoString() }
+
+func (c *CertificateCredentials) String() string {
+	return fmt.Sprintf("{Username:%s Password:%s Certificate:%s}", REDACTED, REDACTED, REDACTED)
+}
+
+func (c *CertificateCredentials) GoString() string {
+	return fmt.Sprintf("&paypal.CertificateCredentials{Username:%q, Password:%q, Certificate:%q}", REDACTED, REDACTED, REDACTED)
+}
@@ -0,0 +1,70 @@
+package paypal_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/crowdmob/paypal"
+)
+
+var secrets = []string{"secret-pass", "secret-sig", "EC-4TX92742UH9911234", "buyer@example.com", "4111111111111111"}
+
+func assertRedacted(t *testing.T, what, printed string) {
+	t.Helper()
+	for _, secret := range secrets {
+		if strings.Contains(printed, secret) {
+			t.Errorf("%s leaks %q: %s", what, secret, printed)
+		}
+	}
+}
+
+func TestPerformRequestDoesNotMutateValues(t *testing.T) {
+	client := fakeNVP(t, nil, url.Values{"ACK": {"Success"}})
+
+	values := url.Values{"METHOD": {"GetExpressCheckoutDetails"}, "TOKEN": {"EC-123"}}
+	if _, err := client.PerformRequestContext(t.Context(), values); err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 {
+		t.Errorf("PerformRequest added to the caller's values: %v", values)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	client := paypal.NewClientWithOptions("api-user", "secret-pass", "secret-sig")
+	assertRedacted(t, "client %v", fmt.Sprintf("%v", client))
+	assertRedacted(t, "client %#v", fmt.Sprintf("%#v", client))
+
+	values := url.Values{
+		"PWD":       {"secret-pass"},
+		"SIGNATURE": {"secret-sig"},
+		"TOKEN":     {"EC-4TX92742UH9911234"},
+		"L_EMAIL0":  {"buyer@example.com"},
+		"ACCT":      {"4111111111111111"},
+		"AMT":       {"10.00"},
+	}
+	printed := fmt.Sprintf("%v %#v", paypal.RedactedValues(values), paypal.RedactedValues(values))
+	assertRedacted(t, "RedactedValues", printed)
+	if !strings.Contains(printed, "1234") || !strings.Contains(printed, "10.00") || !strings.Contains(printed, "b***@example.com") {
+		t.Errorf("RedactedValues should keep the last digits, domains and non-secret values: %s", printed)
+	}
+
+	response := &paypal.PayPalExpressCheckoutDetails{
+		PayPalResponse: paypal.PayPalResponse{Ack: "Success", Values: values},
+		Token:          "EC-4TX92742UH9911234",
+		Email:          "buyer@example.com",
+		FirstName:      "Jane",
+	}
+	var logged bytes.Buffer
+	slog.New(slog.NewTextHandler(&logged, nil)).Info("details", "response", response)
+	for _, printed := range []string{fmt.Sprintf("%v", response), fmt.Sprintf("%#v", response), logged.String()} {
+		assertRedacted(t, "response", printed)
+		if !strings.Contains(printed, "Jane") {
+			t.Errorf("Redacted response should still show its other fields: %s", printed)
+		}
+	}
+}
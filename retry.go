@@ -0,0 +1,156 @@
+package paypal
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// StatusError is returned when the NVP endpoint answers with a non-2xx HTTP
+// status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "paypal: unexpected HTTP status " + strconv.Itoa(e.StatusCode) + " " + e.Status
+}
+
+// RetryPolicy controls how PerformRequest retries transient failures.
+// Methods that don't move money are always retried; money-moving methods only
+// when the request carries a MSGSUBID, which PayPal uses to recognise a
+// repeated request.
+type RetryPolicy struct {
+	MaxAttempts int           // including the first attempt
+	BaseDelay   time.Duration // delay before the first retry, doubled on every further retry
+	MaxDelay    time.Duration
+	Retryable   func(err error) bool // defaults to IsRetryable
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+	}
+}
+
+// NVP methods that are safe to send twice without a MSGSUBID
+var idempotentMethods = map[string]bool{
+	"GetExpressCheckoutDetails": true,
+	"GetTransactionDetails":     true,
+	"TransactionSearch":         true,
+	"GetBalance":                true,
+	"GetPalDetails":             true,
+	"SetExpressCheckout":        true,
+}
+
+// SetRetryPolicy enables retries of transient failures. A nil policy, the
+// default, sends every request once.
+func (pClient *PayPalClient) SetRetryPolicy(policy *RetryPolicy) {
+	pClient.retryPolicy = policy
+}
+
+// IsRetryable reports whether err is a transient failure: a timeout, a
+// refused or dropped connection, an HTTP 5xx, or a PayPal internal error or
+// maintenance response. TLS and certificate failures and bad endpoint URLs
+// are not, since trying again fails the same way.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrCanceled) || errors.Is(err, ErrDeadlineExceeded) {
+		return false
+	}
+
+	var pError *PayPalError
+	if errors.As(err, &pError) {
+		// some of the payments of a partial success went through
+		return errors.Is(pError, ErrInternal) && !strings.EqualFold(pError.Ack, ACK_PARTIAL_SUCCESS)
+	}
+
+	var statusError *StatusError
+	if errors.As(err, &statusError) {
+		return statusError.StatusCode >= 500
+	}
+
+	// http.Client wraps every failure in a *url.Error, which is a net.Error
+	// too, so only the underlying error tells whether it's worth retrying
+	if isTLSError(err) {
+		return false
+	}
+	var netError net.Error
+	if errors.As(err, &netError) && netError.Timeout() {
+		return true
+	}
+	var dnsError *net.DNSError
+	if errors.As(err, &dnsError) {
+		return dnsError.IsTemporary || dnsError.IsTimeout
+	}
+	var opError *net.OpError
+	return errors.As(err, &opError) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isTLSError reports failures of the TLS handshake, such as an untrusted
+// server certificate or PayPal rejecting the client certificate.
+func isTLSError(err error) bool {
+	var verificationError *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameError x509.HostnameError
+	var invalidCertificate x509.CertificateInvalidError
+	var recordHeaderError tls.RecordHeaderError
+	var alertError tls.AlertError
+	if errors.As(err, &verificationError) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameError) ||
+		errors.As(err, &invalidCertificate) || errors.As(err, &recordHeaderError) || errors.As(err, &alertError) {
+		return true
+	}
+
+	// alerts sent by the server, e.g. bad_certificate
+	var opError *net.OpError
+	return errors.As(err, &opError) && opError.Op == "remote error"
+}
+
+func (policy *RetryPolicy) shouldRetry(attempt int, values url.Values, err error) bool {
+	if policy == nil || attempt >= policy.MaxAttempts {
+		return false
+	}
+	if !idempotentMethods[values.Get("METHOD")] && len(values.Get("MSGSUBID")) == 0 {
+		return false
+	}
+
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	return retryable(err)
+}
+
+// wait sleeps before retry number attempt, using exponential backoff with
+// full jitter.
+func (policy *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	delay := policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || (policy.MaxDelay > 0 && delay > policy.MaxDelay) {
+		delay = policy.MaxDelay
+	}
+	if delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay)) + 1)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
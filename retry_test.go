@@ -0,0 +1,102 @@
+package paypal_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/crowdmob/paypal"
+)
+
+// quickRetries makes up to 3 attempts without waiting long between them.
+func quickRetries() fakeNVPOption {
+	return withClientOptions(paypal.WithRetryPolicy(&paypal.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}))
+}
+
+func TestRetryIdempotentMethod(t *testing.T) {
+	var calls int
+	client := fakeNVP(t, func(url.Values) { calls++ }, url.Values{"ACK": {"Success"}, "TOKEN": {"EC-123"}}, failFirst(2, http.StatusServiceUnavailable), quickRetries())
+
+	response, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123")
+	if err != nil {
+		t.Fatalf("Expected the third attempt to succeed, got %v", err)
+	}
+	if calls != 3 || response.Token != "EC-123" {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+
+	calls = 0
+	client = fakeNVP(t, func(url.Values) { calls++ }, url.Values{"ACK": {"Success"}, "TOKEN": {"EC-123"}}, failFirst(3, http.StatusServiceUnavailable), quickRetries())
+	_, err = client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123")
+	var statusError *paypal.StatusError
+	if !errors.As(err, &statusError) || calls != 3 {
+		t.Errorf("Expected a StatusError after 3 calls, got %v after %d", err, calls)
+	}
+}
+
+func TestRetryMoneyMovingMethodNeedsMsgSubId(t *testing.T) {
+	var calls int
+	client := fakeNVP(t, func(url.Values) { calls++ }, url.Values{"ACK": {"Success"}, "TOKEN": {"EC-123"}}, failFirst(1, http.StatusServiceUnavailable), quickRetries())
+
+	values := url.Values{"METHOD": {"DoCapture"}, "AUTHORIZATIONID": {"AUTH"}, "AMT": {"1.00"}}
+	if _, err := client.PerformRequestContext(t.Context(), values); err == nil || calls != 1 {
+		t.Errorf("DoCapture without MSGSUBID should not be retried, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	client = fakeNVP(t, func(url.Values) { calls++ }, url.Values{"ACK": {"Success"}, "TOKEN": {"EC-123"}}, failFirst(1, http.StatusServiceUnavailable), quickRetries())
+	if _, err := client.RefundTransactionContext(t.Context(), paypal.Money{}, paypal.Money{}, paypal.Money{}, "TX", "", "refund-1", false); err != nil || calls != 2 {
+		t.Errorf("RefundTransaction with MSGSUBID should be retried, got %v after %d calls", err, calls)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := map[error]bool{
+		&paypal.PayPalError{}: true,
+		&paypal.PayPalError{Ack: "Failure", ErrorCode: paypal.ERROR_CODE_INTERNAL_ERROR, ShortMessage: "Internal Error"}:   true,
+		&paypal.PayPalError{Ack: "Failure", ErrorCode: paypal.ERROR_CODE_FUNDING_FAILURE, ShortMessage: "Funding failure"}: false,
+		&paypal.StatusError{StatusCode: 502}:                                                              true,
+		&paypal.StatusError{StatusCode: 404}:                                                              false,
+		&paypal.ValidationError{Field: "AMT"}:                                                             false,
+		&url.Error{Op: "Post", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}}:                    true,
+		&url.Error{Op: "Post", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}:                  true,
+		&url.Error{Op: "Post", Err: io.EOF}:                                                               true,
+		&url.Error{Op: "Post", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}: false,
+		&url.Error{Op: "Post", Err: &net.OpError{Op: "remote error", Err: tls.AlertError(42)}}:            false,
+		&url.Error{Op: "Post", Err: errors.New(`unsupported protocol scheme "htp"`)}:                      false,
+		&url.Error{Op: "Post", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}:                 false,
+		paypal.ErrCanceled: false,
+	}
+	for err, want := range cases {
+		if got := paypal.IsRetryable(err); got != want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
+
+func TestUntrustedCertificateIsNotRetried(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	attempts := 0
+	client := paypal.NewClientWithOptions("user", "pass", "sig", paypal.WithEndpoint(server.URL))
+	client.SetRetryPolicy(&paypal.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Retryable: func(err error) bool {
+		attempts++
+		return paypal.IsRetryable(err)
+	}})
+
+	if _, err := client.GetExpressCheckoutDetailsContext(t.Context(), "EC-123"); err == nil || attempts != 1 {
+		t.Errorf("Expected a single failed attempt, got %d and %v", attempts, err)
+	}
+}
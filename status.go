@@ -0,0 +1,266 @@
+package paypal
+
+import "strings"
+
+// PaymentStatus is PAYMENTSTATUS. Values PayPal spells differently, like
+// "Partially_Refunded" or "completed", are normalized to the constants
+// below; values that aren't known are kept as returned.
+type PaymentStatus string
+
+const (
+	PAYMENT_STATUS_NONE               PaymentStatus = "None"
+	PAYMENT_STATUS_CANCELED_REVERSAL  PaymentStatus = "Canceled-Reversal"
+	PAYMENT_STATUS_COMPLETED          PaymentStatus = "Completed"
+	PAYMENT_STATUS_DENIED             PaymentStatus = "Denied"
+	PAYMENT_STATUS_EXPIRED            PaymentStatus = "Expired"
+	PAYMENT_STATUS_FAILED             PaymentStatus = "Failed"
+	PAYMENT_STATUS_IN_PROGRESS        PaymentStatus = "In-Progress"
+	PAYMENT_STATUS_PARTIALLY_REFUNDED PaymentStatus = "Partially-Refunded"
+	PAYMENT_STATUS_PENDING            PaymentStatus = "Pending"
+	PAYMENT_STATUS_REFUNDED           PaymentStatus = "Refunded"
+	PAYMENT_STATUS_REVERSED           PaymentStatus = "Reversed"
+	PAYMENT_STATUS_PROCESSED          PaymentStatus = "Processed"
+	PAYMENT_STATUS_VOIDED             PaymentStatus = "Voided"
+)
+
+// PendingReason is PENDINGREASON, only set when the payment is pending.
+type PendingReason string
+
+const (
+	PENDING_REASON_NONE              PendingReason = "none"
+	PENDING_REASON_ADDRESS           PendingReason = "address" // unconfirmed shipping address, accept or deny manually
+	PENDING_REASON_AUTHORIZATION     PendingReason = "authorization"
+	PENDING_REASON_ECHECK            PendingReason = "echeck"
+	PENDING_REASON_INTL              PendingReason = "intl" // accept or deny manually
+	PENDING_REASON_MULTI_CURRENCY    PendingReason = "multi-currency"
+	PENDING_REASON_ORDER             PendingReason = "order"
+	PENDING_REASON_PAYMENT_REVIEW    PendingReason = "payment-review" // under risk review by PayPal
+	PENDING_REASON_REGULATORY_REVIEW PendingReason = "regulatory-review"
+	PENDING_REASON_UNILATERAL        PendingReason = "unilateral"
+	PENDING_REASON_VERIFY            PendingReason = "verify"
+	PENDING_REASON_OTHER             PendingReason = "other"
+)
+
+// ReasonCode is REASONCODE, the reason for a reversal.
+type ReasonCode string
+
+const (
+	REASON_CODE_NONE            ReasonCode = "none"
+	REASON_CODE_CHARGEBACK      ReasonCode = "chargeback"
+	REASON_CODE_GUARANTEE       ReasonCode = "guarantee"
+	REASON_CODE_BUYER_COMPLAINT ReasonCode = "buyer-complaint"
+	REASON_CODE_REFUND          ReasonCode = "refund"
+	REASON_CODE_OTHER           ReasonCode = "other"
+)
+
+// ProtectionEligibility is PROTECTIONELIGIBILITY, the seller protection the
+// payment is eligible for.
+type ProtectionEligibility string
+
+const (
+	PROTECTION_ELIGIBLE           ProtectionEligibility = "Eligible"
+	PROTECTION_PARTIALLY_ELIGIBLE ProtectionEligibility = "PartiallyEligible"
+	PROTECTION_INELIGIBLE         ProtectionEligibility = "Ineligible"
+)
+
+// TransactionType is TRANSACTIONTYPE.
+type TransactionType string
+
+const (
+	TRANSACTION_TYPE_CART             TransactionType = "cart"
+	TRANSACTION_TYPE_EXPRESS_CHECKOUT TransactionType = "express-checkout"
+)
+
+// CheckoutStatus is CHECKOUTSTATUS, how far an Express Checkout has got.
+type CheckoutStatus string
+
+const (
+	CHECKOUT_STATUS_NOT_INITIATED CheckoutStatus = "PaymentActionNotInitiated"
+	CHECKOUT_STATUS_FAILED        CheckoutStatus = "PaymentActionFailed"
+	CHECKOUT_STATUS_IN_PROGRESS   CheckoutStatus = "PaymentActionInProgress"
+	CHECKOUT_STATUS_COMPLETED     CheckoutStatus = "PaymentActionCompleted"
+)
+
+var (
+	paymentStatuses = enumValues(
+		PAYMENT_STATUS_NONE, PAYMENT_STATUS_CANCELED_REVERSAL, PAYMENT_STATUS_COMPLETED, PAYMENT_STATUS_DENIED,
+		PAYMENT_STATUS_EXPIRED, PAYMENT_STATUS_FAILED, PAYMENT_STATUS_IN_PROGRESS, PAYMENT_STATUS_PARTIALLY_REFUNDED,
+		PAYMENT_STATUS_PENDING, PAYMENT_STATUS_REFUNDED, PAYMENT_STATUS_REVERSED, PAYMENT_STATUS_PROCESSED,
+		PAYMENT_STATUS_VOIDED,
+	)
+	pendingReasons = enumValues(
+		PENDING_REASON_NONE, PENDING_REASON_ADDRESS, PENDING_REASON_AUTHORIZATION, PENDING_REASON_ECHECK,
+		PENDING_REASON_INTL, PENDING_REASON_MULTI_CURRENCY, PENDING_REASON_ORDER, PENDING_REASON_PAYMENT_REVIEW,
+		PENDING_REASON_REGULATORY_REVIEW, PENDING_REASON_UNILATERAL, PENDING_REASON_VERIFY, PENDING_REASON_OTHER,
+	)
+	reasonCodes = enumValues(
+		REASON_CODE_NONE, REASON_CODE_CHARGEBACK, REASON_CODE_GUARANTEE, REASON_CODE_BUYER_COMPLAINT,
+		REASON_CODE_REFUND, REASON_CODE_OTHER,
+	)
+	protectionEligibilities = enumValues(PROTECTION_ELIGIBLE, PROTECTION_PARTIALLY_ELIGIBLE, PROTECTION_INELIGIBLE)
+	transactionTypes        = enumValues(TRANSACTION_TYPE_CART, TRANSACTION_TYPE_EXPRESS_CHECKOUT)
+	checkoutStatuses        = enumValues(
+		CHECKOUT_STATUS_NOT_INITIATED, CHECKOUT_STATUS_FAILED, CHECKOUT_STATUS_IN_PROGRESS, CHECKOUT_STATUS_COMPLETED,
+	)
+)
+
+func init() {
+	// older API versions and documentation spell it this way
+	paymentStatuses[enumKey("Cancel-Reversal")] = PAYMENT_STATUS_CANCELED_REVERSAL
+	checkoutStatuses[enumKey("PaymentCompleted")] = CHECKOUT_STATUS_COMPLETED
+}
+
+// enumValues maps the normalized spelling of each value to the value.
+func enumValues[T ~string](values ...T) map[string]T {
+	m := make(map[string]T, len(values))
+	for _, value := range values {
+		m[enumKey(string(value))] = value
+	}
+	return m
+}
+
+// enumKey ignores case and separators: "Partially-Refunded",
+// "partially_refunded" and "PartiallyRefunded" are the same value.
+func enumKey(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(value)))
+}
+
+func parseEnum[T ~string](values map[string]T, raw string) T {
+	if value, ok := values[enumKey(raw)]; ok {
+		return value
+	}
+	return T(raw)
+}
+
+func ParsePaymentStatus(raw string) PaymentStatus {
+	return parseEnum(paymentStatuses, raw)
+}
+
+func ParsePendingReason(raw string) PendingReason {
+	return parseEnum(pendingReasons, raw)
+}
+
+func ParseReasonCode(raw string) ReasonCode {
+	return parseEnum(reasonCodes, raw)
+}
+
+func ParseProtectionEligibility(raw string) ProtectionEligibility {
+	return parseEnum(protectionEligibilities, raw)
+}
+
+func ParseTransactionType(raw string) TransactionType {
+	return parseEnum(transactionTypes, raw)
+}
+
+func ParseCheckoutStatus(raw string) CheckoutStatus {
+	return parseEnum(checkoutStatuses, raw)
+}
+
+// Known reports whether s is one of the PAYMENT_STATUS_ constants, rather
+// than a value PayPal added since.
+func (s PaymentStatus) Known() bool {
+	_, ok := paymentStatuses[enumKey(string(s))]
+	return ok && len(s) != 0
+}
+
+func (r PendingReason) Known() bool {
+	_, ok := pendingReasons[enumKey(string(r))]
+	return ok && len(r) != 0
+}
+
+func (c ReasonCode) Known() bool {
+	_, ok := reasonCodes[enumKey(string(c))]
+	return ok && len(c) != 0
+}
+
+func (e ProtectionEligibility) Known() bool {
+	_, ok := protectionEligibilities[enumKey(string(e))]
+	return ok && len(e) != 0
+}
+
+func (t TransactionType) Known() bool {
+	_, ok := transactionTypes[enumKey(string(t))]
+	return ok && len(t) != 0
+}
+
+func (s CheckoutStatus) Known() bool {
+	_, ok := checkoutStatuses[enumKey(string(s))]
+	return ok && len(s) != 0
+}
+
+// IsFinal reports whether the payment won't change status any more without
+// a new action such as a refund.
+func (s PaymentStatus) IsFinal() bool {
+	switch s {
+	case PAYMENT_STATUS_COMPLETED, PAYMENT_STATUS_CANCELED_REVERSAL, PAYMENT_STATUS_DENIED, PAYMENT_STATUS_EXPIRED,
+		PAYMENT_STATUS_FAILED, PAYMENT_STATUS_REFUNDED, PAYMENT_STATUS_REVERSED, PAYMENT_STATUS_VOIDED:
+		return true
+	}
+	return false
+}
+
+// IsRefundable reports whether RefundTransaction can be called for the
+// payment.
+func (s PaymentStatus) IsRefundable() bool {
+	return s == PAYMENT_STATUS_COMPLETED || s == PAYMENT_STATUS_PARTIALLY_REFUNDED
+}
+
+// IsCompleted reports whether DoExpressCheckoutPayment succeeded for the
+// checkout, so calling it again would pay twice.
+func (s CheckoutStatus) IsCompleted() bool {
+	return s == CHECKOUT_STATUS_COMPLETED
+}
+
+// NeedsManualReview reports whether the payment waits for the merchant to
+// accept or deny it, or for PayPal to review it.
+func (r PendingReason) NeedsManualReview() bool {
+	switch r {
+	case PENDING_REASON_ADDRESS, PENDING_REASON_INTL, PENDING_REASON_MULTI_CURRENCY,
+		PENDING_REASON_PAYMENT_REVIEW, PENDING_REASON_REGULATORY_REVIEW:
+		return true
+	}
+	return false
+}
+
+// NeedsManualReview reports whether the payment is pending for a reason
+// that needs a review before the order is fulfilled.
+func (info *PaymentInfo) NeedsManualReview() bool {
+	return info.PaymentStatus == PAYMENT_STATUS_PENDING && info.PendingReason.NeedsManualReview()
+}
+
+// UnmarshalNVP normalizes the values decoded with the nvp package.
+func (s *PaymentStatus) UnmarshalNVP(value string) error {
+	*s = ParsePaymentStatus(value)
+	return nil
+}
+
+func (r *PendingReason) UnmarshalNVP(value string) error {
+	*r = ParsePendingReason(value)
+	return nil
+}
+
+func (c *ReasonCode) UnmarshalNVP(value string) error {
+	*c = ParseReasonCode(value)
+	return nil
+}
+
+func (e *ProtectionEligibility) UnmarshalNVP(value string) error {
+	*e = ParseProtectionEligibility(value)
+	return nil
+}
+
+func (t *TransactionType) UnmarshalNVP(value string) error {
+	*t = ParseTransactionType(value)
+	return nil
+}
+
+func (s *CheckoutStatus) UnmarshalNVP(value string) error {
+	*s = ParseCheckoutStatus(value)
+	return nil
+}
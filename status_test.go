@@ -0,0 +1,57 @@
+package paypal_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/crowdmob/paypal"
+)
+
+func TestParsePaymentStatus(t *testing.T) {
+	for raw, expected := range map[string]paypal.PaymentStatus{
+		"Completed":          paypal.PAYMENT_STATUS_COMPLETED,
+		"completed":          paypal.PAYMENT_STATUS_COMPLETED,
+		"Partially_Refunded": paypal.PAYMENT_STATUS_PARTIALLY_REFUNDED,
+		"PartiallyRefunded":  paypal.PAYMENT_STATUS_PARTIALLY_REFUNDED,
+		"Cancel-Reversal":    paypal.PAYMENT_STATUS_CANCELED_REVERSAL,
+		"In-Progress":        paypal.PAYMENT_STATUS_IN_PROGRESS,
+	} {
+		if status := paypal.ParsePaymentStatus(raw); status != expected || !status.Known() {
+			t.Errorf("Expected %q to parse as %q, got %q", raw, expected, status)
+		}
+	}
+
+	if status := paypal.ParsePaymentStatus("Held-For-Review"); status != "Held-For-Review" || status.Known() {
+		t.Errorf("Expected an unknown status to be kept as is, got %q", status)
+	}
+	if status := paypal.ParsePaymentStatus(""); status.Known() {
+		t.Error("Expected an empty status to be unknown")
+	}
+}
+
+func TestPaymentInfoPredicates(t *testing.T) {
+	client := fakeNVP(t, nil, url.Values{
+		"ACK":                   {"Success"},
+		"TRANSACTIONID":         {"TX0"},
+		"AMT":                   {"5.00"},
+		"CURRENCYCODE":          {"USD"},
+		"PAYMENTSTATUS":         {"Pending"},
+		"PENDINGREASON":         {"paymentreview"},
+		"PROTECTIONELIGIBILITY": {"partiallyeligible"},
+	})
+	response, err := client.DoReferenceTransactionContext(t.Context(), "B-123", "Sale", paypal.NewMoney(500, "USD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.PendingReason != paypal.PENDING_REASON_PAYMENT_REVIEW || response.ProtectionEligibility != paypal.PROTECTION_PARTIALLY_ELIGIBLE {
+		t.Errorf("Expected normalized values, got %q and %q", response.PendingReason, response.ProtectionEligibility)
+	}
+	if !response.NeedsManualReview() || response.PaymentStatus.IsFinal() || response.PaymentStatus.IsRefundable() {
+		t.Errorf("Unexpected predicates for a payment under review: %+v", response.PaymentInfo)
+	}
+
+	if !paypal.PAYMENT_STATUS_COMPLETED.IsFinal() || !paypal.PAYMENT_STATUS_COMPLETED.IsRefundable() || paypal.PAYMENT_STATUS_REFUNDED.IsRefundable() {
+		t.Error("Unexpected predicates for completed and refunded payments")
+	}
+}
@@ -0,0 +1,41 @@
+package paypal
+
+import (
+	"time"
+
+	"github.com/crowdmob/paypal/nvp"
+)
+
+// TIMESTAMP_FORMAT is the UTC ISO-8601 format PayPal sends TIMESTAMP,
+// ORDERTIME and the other times in NVP responses in, e.g.
+// "2014-01-28T22:41:39Z". Any RFC 3339 time is accepted when parsing them.
+const TIMESTAMP_FORMAT = nvp.TIME_FORMAT
+
+// TimestampError is set as TimestampErr or OrderTimeErr when a time in an
+// NVP response isn't an RFC 3339 time, with the zero time in place of the
+// malformed one. It doesn't fail the call, which PayPal has acknowledged.
+type TimestampError struct {
+	Field string
+	Value string
+}
+
+func (e *TimestampError) Error() string {
+	return "paypal: unexpected " + e.Field + " format " + `"` + e.Value + `"` + ", expected an RFC 3339 time"
+}
+
+// ParseTimestamp parses the RFC 3339 time in field of an NVP response and
+// returns it in UTC. Empty values are the zero time.
+func ParseTimestamp(field, value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, &TimestampError{Field: field, Value: value}
+	}
+	return t.UTC(), nil
+}
+
+func (info *PaymentInfo) parseOrderTime(prefix string) {
+	info.OrderTime, info.OrderTimeErr = ParseTimestamp(prefix+"ORDERTIME", info.RawOrderTime)
+}
@@ -0,0 +1,90 @@
+package paypal
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Tracer starts a span for every NVP call. The parent span is the one in the
+// request context. An OpenTelemetry tracer can be adapted in a few lines:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, paypal.Span) {
+//		ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced NVP call.
+type Span interface {
+	SetAttribute(key, value string)
+	RecordError(err error)
+	End()
+}
+
+// Attributes set on NVP call spans
+const (
+	SPAN_ATTRIBUTE_METHOD         = "paypal.method"
+	SPAN_ATTRIBUTE_CORRELATION_ID = "paypal.correlation_id"
+	SPAN_ATTRIBUTE_ACK            = "paypal.ack"
+	SPAN_ATTRIBUTE_ERROR_CODE     = "paypal.error_code" // comma separated if there are several
+)
+
+type noopTracer struct{}
+
+type noopSpan struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopSpan) SetAttribute(key, value string) {}
+func (noopSpan) RecordError(err error)          {}
+func (noopSpan) End()                           {}
+
+// WithTracer traces every NVP call, retries included, as a span named
+// "PayPal " followed by the METHOD.
+func WithTracer(tracer Tracer) ClientOption {
+	return func(pClient *PayPalClient) {
+		pClient.tracer = tracer
+	}
+}
+
+func (pClient *PayPalClient) startSpan(ctx context.Context, values url.Values) (context.Context, Span) {
+	tracer := pClient.tracer
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+
+	method := values.Get("METHOD")
+	ctx, span := tracer.Start(ctx, "PayPal "+method)
+	span.SetAttribute(SPAN_ATTRIBUTE_METHOD, method)
+	return ctx, span
+}
+
+func endSpan(span Span, response *PayPalResponse, err error) {
+	var codes []string
+	if response != nil {
+		span.SetAttribute(SPAN_ATTRIBUTE_ACK, response.Ack)
+		if len(response.CorrelationId) != 0 {
+			span.SetAttribute(SPAN_ATTRIBUTE_CORRELATION_ID, response.CorrelationId)
+		}
+		codes = errorCodes(response.Warnings)
+	}
+	var pError *PayPalError
+	if errors.As(err, &pError) {
+		codes = errorCodes(pError.Errors)
+	}
+	if len(codes) != 0 {
+		span.SetAttribute(SPAN_ATTRIBUTE_ERROR_CODE, strings.Join(codes, ","))
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
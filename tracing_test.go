@@ -0,0 +1,67 @@
+package paypal_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/crowdmob/paypal"
+)
+
+type spanKey struct{}
+
+type recordedSpan struct {
+	name       string
+	parent     string
+	attributes map[string]string
+	err        error
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttribute(key, value string) { s.attributes[key] = value }
+func (s *recordedSpan) RecordError(err error)          { s.err = err }
+func (s *recordedSpan) End()                           { s.ended = true }
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, paypal.Span) {
+	parent, _ := ctx.Value(spanKey{}).(string)
+	span := &recordedSpan{name: name, parent: parent, attributes: make(map[string]string)}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, name), span
+}
+
+func TestTracing(t *testing.T) {
+	tracer := &recordingTracer{}
+	var requestSpan string
+	client := fakeNVPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ACK=Failure&CORRELATIONID=abc123&L_ERRORCODE0=10410&L_SHORTMESSAGE0=Invalid+token&L_ERRORCODE1=10411&L_SHORTMESSAGE1=Session+expired"))
+	}, paypal.WithTracer(tracer), paypal.WithMiddleware(func(next paypal.RoundTripper) paypal.RoundTripper {
+		return paypal.RoundTripperFunc(func(ctx context.Context, values url.Values) (*paypal.PayPalResponse, error) {
+			requestSpan, _ = ctx.Value(spanKey{}).(string)
+			return next.RoundTrip(ctx, values)
+		})
+	}))
+
+	ctx := context.WithValue(t.Context(), spanKey{}, "checkout")
+	if _, err := client.GetExpressCheckoutDetailsContext(ctx, "EC-123"); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("Expected one span, got %d", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.name != "PayPal GetExpressCheckoutDetails" || span.parent != "checkout" || !span.ended || span.err == nil {
+		t.Errorf("Unexpected span %+v", span)
+	}
+	if span.attributes[paypal.SPAN_ATTRIBUTE_CORRELATION_ID] != "abc123" || span.attributes[paypal.SPAN_ATTRIBUTE_ACK] != "Failure" || span.attributes[paypal.SPAN_ATTRIBUTE_ERROR_CODE] != "10410,10411" {
+		t.Errorf("Unexpected span attributes %v", span.attributes)
+	}
+	if requestSpan != "PayPal GetExpressCheckoutDetails" {
+		t.Errorf("Expected the request to be sent within the span, got %q", requestSpan)
+	}
+}